@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+)
+
+// OverflowPolicy defines what an AsyncLogger does with a message when its
+// queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock causes Print to wait until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest causes the message being printed to be discarded.
+	OverflowDropNewest
+	// OverflowDropOldest causes the oldest queued message to be discarded
+	// to make room for the new one.
+	OverflowDropOldest
+)
+
+type asyncOptions struct {
+	queueSize int
+	batchSize int
+	overflow  OverflowPolicy
+}
+
+// An AsyncOption is used to change the default behaviour of the AsyncLogger.
+type AsyncOption struct {
+	f func(o *asyncOptions)
+}
+
+// QueueSize sets the maximum number of messages waiting to be written. The
+// default is 1024.
+func QueueSize(n int) AsyncOption {
+	return AsyncOption{func(o *asyncOptions) {
+		o.queueSize = n
+	}}
+}
+
+// BatchSize sets the maximum number of queued messages that are handed to
+// the underlying logger at once. The default is 128.
+func BatchSize(n int) AsyncOption {
+	return AsyncOption{func(o *asyncOptions) {
+		o.batchSize = n
+	}}
+}
+
+// Overflow sets the policy for dealing with messages printed while the queue
+// is full. By default, Print blocks until there is room.
+func Overflow(p OverflowPolicy) AsyncOption {
+	return AsyncOption{func(o *asyncOptions) {
+		o.overflow = p
+	}}
+}
+
+// A BatchLogger is a Logger that can write multiple messages at once, more
+// efficiently than printing them one by one.
+type BatchLogger interface {
+	Logger
+	PrintBatch(messages []string)
+}
+
+// AsyncLogger is a Logger that queues its messages and writes them to
+// another logger in a background goroutine, keeping slow sinks off the
+// request path. If the underlying logger is a BatchLogger, queued messages
+// are written in batches. Whenever messages are dropped due to the overflow
+// policy, the number of dropped messages is reported to the underlying
+// logger once there is room again.
+type AsyncLogger struct {
+	logger Logger
+	o      asyncOptions
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+	queue    []string
+	dropped  int
+	writing  bool
+	closed   bool
+	done     chan struct{}
+}
+
+// NewAsyncLogger creates a new asynchronous logger that writes to l, and
+// starts its background goroutine. It has to be closed in order to write out
+// any queued messages.
+func NewAsyncLogger(l Logger, opts ...AsyncOption) *AsyncLogger {
+	o := asyncOptions{queueSize: 1024, batchSize: 128}
+	for _, op := range opts {
+		op.f(&o)
+	}
+
+	if o.queueSize < 1 {
+		o.queueSize = 1
+	}
+
+	if o.batchSize < 1 {
+		o.batchSize = 1
+	}
+
+	a := &AsyncLogger{logger: l, o: o, done: make(chan struct{})}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	a.idle = sync.NewCond(&a.mu)
+
+	go a.run()
+
+	return a
+}
+
+// Print queues the message for writing. Messages printed after the logger
+// has been closed are written synchronously, once all queued messages have
+// been written.
+func (a *AsyncLogger) Print(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+
+	a.mu.Lock()
+
+	for !a.closed && len(a.queue) >= a.o.queueSize {
+		switch a.o.overflow {
+		case OverflowDropNewest:
+			a.dropped++
+			a.mu.Unlock()
+			return
+		case OverflowDropOldest:
+			a.queue = a.queue[1:]
+			a.dropped++
+		default:
+			a.notFull.Wait()
+		}
+	}
+
+	if a.closed {
+		a.mu.Unlock()
+
+		// Keep the message behind the ones still being drained
+		<-a.done
+		a.logger.Print(msg)
+		return
+	}
+
+	a.queue = append(a.queue, msg)
+	a.notEmpty.Signal()
+	a.mu.Unlock()
+}
+
+// Flush blocks until all messages queued so far have been written.
+func (a *AsyncLogger) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for len(a.queue) > 0 || a.writing {
+		a.idle.Wait()
+	}
+}
+
+// Close writes out all queued messages and stops the background goroutine.
+// It does not close the underlying logger.
+func (a *AsyncLogger) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+
+	return nil
+}
+
+func (a *AsyncLogger) run() {
+	defer close(a.done)
+
+	for {
+		a.mu.Lock()
+		for len(a.queue) == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+
+		if len(a.queue) == 0 {
+			dropped := a.dropped
+			a.dropped = 0
+			a.mu.Unlock()
+
+			// Don't lose the count of any messages dropped after the last
+			// batch was taken
+			a.reportDropped(dropped)
+			return
+		}
+
+		n := len(a.queue)
+		if n > a.o.batchSize {
+			n = a.o.batchSize
+		}
+
+		batch := make([]string, n)
+		copy(batch, a.queue)
+		a.queue = a.queue[n:]
+
+		dropped := a.dropped
+		a.dropped = 0
+		a.writing = true
+
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		a.write(batch)
+
+		a.reportDropped(dropped)
+
+		a.mu.Lock()
+		a.writing = false
+		a.idle.Broadcast()
+		a.mu.Unlock()
+	}
+}
+
+func (a *AsyncLogger) reportDropped(dropped int) {
+	if dropped > 0 {
+		a.logger.Print(fmt.Sprintf("async logger: dropped %d messages", dropped))
+	}
+}
+
+func (a *AsyncLogger) write(batch []string) {
+	if b, ok := a.logger.(BatchLogger); ok {
+		b.PrintBatch(batch)
+		return
+	}
+
+	for _, msg := range batch {
+		a.logger.Print(msg)
+	}
+}
@@ -0,0 +1,216 @@
+package handler_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+type recordLogger struct {
+	mu       sync.Mutex
+	messages []string
+	batches  [][]string
+}
+
+func (l *recordLogger) Print(v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, fmt.Sprint(v...))
+}
+
+func (l *recordLogger) PrintBatch(messages []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, messages...)
+	l.batches = append(l.batches, messages)
+}
+
+// blockingLogger blocks on the first message until released
+type blockingLogger struct {
+	recorded recordLogger
+	once     sync.Once
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (l *blockingLogger) Print(v ...interface{}) {
+	l.once.Do(func() {
+		close(l.started)
+		<-l.release
+	})
+
+	l.recorded.Print(v...)
+}
+
+func TestAsyncLogger(t *testing.T) {
+	l := &recordLogger{}
+	a := handler.NewAsyncLogger(l, handler.BatchSize(10))
+
+	var expected []string
+	for i := 0; i < 100; i++ {
+		msg := fmt.Sprintf("message %d", i)
+		expected = append(expected, msg)
+		a.Print(msg)
+	}
+
+	a.Flush()
+
+	if !reflect.DeepEqual(l.messages, expected) {
+		t.Fatalf("expected %v, got %v", expected, l.messages)
+	}
+
+	for _, b := range l.batches {
+		if len(b) > 10 {
+			t.Fatalf("expected batches of at most 10 messages, got %d", len(b))
+		}
+	}
+
+	a.Print("late")
+	a.Close()
+
+	if last := l.messages[len(l.messages)-1]; last != "late" {
+		t.Fatalf("expected the message to be written on close, got %q", last)
+	}
+
+	a.Print("closed")
+
+	if last := l.messages[len(l.messages)-1]; last != "closed" {
+		t.Fatalf("expected the message to be written synchronously, got %q", last)
+	}
+}
+
+func TestAsyncLoggerOverflow(t *testing.T) {
+	cases := []struct {
+		policy   handler.OverflowPolicy
+		expected []string
+	}{
+		{handler.OverflowDropNewest, []string{"a", "b", "async logger: dropped 1 messages", "c"}},
+		{handler.OverflowDropOldest, []string{"a", "c", "async logger: dropped 1 messages", "d"}},
+		{handler.OverflowBlock, []string{"a", "b", "c", "d"}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &blockingLogger{started: make(chan struct{}), release: make(chan struct{})}
+			a := handler.NewAsyncLogger(l, handler.QueueSize(2), handler.BatchSize(1), handler.Overflow(tc.policy))
+
+			a.Print("a")
+			<-l.started
+
+			a.Print("b")
+			a.Print("c")
+
+			if tc.policy == handler.OverflowBlock {
+				done := make(chan struct{})
+				go func() {
+					a.Print("d")
+					close(done)
+				}()
+
+				select {
+				case <-done:
+					t.Fatalf("expected print to block")
+				default:
+				}
+
+				close(l.release)
+				<-done
+			} else {
+				a.Print("d")
+				close(l.release)
+			}
+
+			a.Close()
+
+			if !reflect.DeepEqual(l.recorded.messages, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, l.recorded.messages)
+			}
+		})
+	}
+}
+
+func TestAsyncLoggerOverflowOnClose(t *testing.T) {
+	for _, policy := range []handler.OverflowPolicy{handler.OverflowDropNewest, handler.OverflowDropOldest} {
+		t.Run(fmt.Sprint(policy), func(t *testing.T) {
+			l := &blockingLogger{started: make(chan struct{}), release: make(chan struct{})}
+			a := handler.NewAsyncLogger(l, handler.QueueSize(2), handler.BatchSize(1), handler.Overflow(policy))
+
+			a.Print("first")
+			<-l.started
+
+			const printers, messages = 8, 50
+
+			var wg sync.WaitGroup
+			for i := 0; i < printers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < messages; j++ {
+						a.Print("message")
+					}
+				}()
+			}
+
+			closed := make(chan struct{})
+			go func() {
+				a.Close()
+				close(closed)
+			}()
+
+			// Printers that are late for the queue wait for it to be drained
+			close(l.release)
+			wg.Wait()
+			<-closed
+
+			written, dropped := 0, 0
+			for _, msg := range l.recorded.messages {
+				var n int
+				if _, err := fmt.Sscanf(msg, "async logger: dropped %d messages", &n); err == nil {
+					dropped += n
+				} else {
+					written++
+				}
+			}
+
+			if total := 1 + printers*messages; written+dropped != total {
+				t.Fatalf("expected %d messages to be written or reported as dropped, got %d and %d", total, written, dropped)
+			}
+		})
+	}
+}
+
+func TestAsyncLoggerPrintOnClose(t *testing.T) {
+	l := &blockingLogger{started: make(chan struct{}), release: make(chan struct{})}
+	a := handler.NewAsyncLogger(l, handler.QueueSize(1), handler.BatchSize(1))
+
+	a.Print("a")
+	<-l.started
+
+	a.Print("b")
+
+	// Blocks on the full queue, until woken up by Close
+	printed := make(chan struct{})
+	go func() {
+		a.Print("c")
+		close(printed)
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		a.Close()
+		close(closed)
+	}()
+
+	close(l.release)
+	<-printed
+	<-closed
+
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(l.recorded.messages, expected) {
+		t.Fatalf("expected %v, got %v", expected, l.recorded.messages)
+	}
+}
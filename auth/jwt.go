@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -66,6 +67,16 @@ func Logger(l handler.Logger) TokenOpt {
 	}}
 }
 
+// StructuredLogger defines a logger that will receive invalid requests and
+// authentication errors as leveled messages, along with the request method
+// and path. If set, it takes precedence over the one provided by the Logger
+// option.
+func StructuredLogger(l handler.StructuredLogger) TokenOpt {
+	return TokenOpt{func(o *options) {
+		o.slogger = l
+	}}
+}
+
 // Expiration sets the expiration time of the auth token
 func Expiration(e time.Duration) TokenOpt {
 	return TokenOpt{func(o *options) {
@@ -111,6 +122,7 @@ func Extractor(e request.Extractor) TokenOpt {
 
 type options struct {
 	logger     handler.Logger
+	slogger    handler.StructuredLogger
 	claimer    func(*jwt.StandardClaims) jwt.Claims
 	expiration time.Duration
 	issuer     string
@@ -157,7 +169,8 @@ func TokenGenerator(h http.Handler, auth Authenticator, secret []byte, opts ...T
 			err = r.ParseForm()
 		}
 		if err != nil {
-			o.logger.Print("Invalid request form: ", err)
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelWarn, "invalid request form", append(handler.RequestFields(r),
+				handler.Field{Key: "error", Value: err})...)
 
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -189,7 +202,9 @@ func TokenGenerator(h http.Handler, auth Authenticator, secret []byte, opts ...T
 
 			h.ServeHTTP(w, r)
 		} else {
-			o.logger.Print("Error authenticating user:", err)
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "error authenticating user", append(handler.RequestFields(r),
+				handler.Field{Key: "user", Value: user},
+				handler.Field{Key: "error", Value: err})...)
 
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -216,7 +231,8 @@ func TokenBlacklister(h http.Handler, store TokenStorage, secret []byte, opts ..
 
 		tokenStr, err := o.extractor.ExtractToken(r)
 		if err != nil {
-			o.logger.Print("Invalid request: ", err)
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelWarn, "invalid request", append(handler.RequestFields(r),
+				handler.Field{Key: "error", Value: err})...)
 
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -266,7 +282,8 @@ func TokenBlacklister(h http.Handler, store TokenStorage, secret []byte, opts ..
 		}
 
 		if err != nil {
-			o.logger.Print("Error authenticating user: ", err)
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "error blacklisting token", append(handler.RequestFields(r),
+				handler.Field{Key: "error", Value: err})...)
 
 			http.Error(w, err.Error(), errCode)
 			return
@@ -307,6 +324,11 @@ func RequireToken(h http.Handler, validator TokenValidator, secret []byte, opts
 			if tok, err := o.extractor.ExtractToken(r); err == nil && validator.Validate(tok, token.Claims) {
 				r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, token.Claims))
 				allowed = true
+
+				// Make the subject visible to any wrapping access log
+				if sub := SubjectUser(r); sub != "" {
+					handler.SetUser(r, sub)
+				}
 			}
 		} else if ve, ok := err.(*jwt.ValidationError); ok {
 			if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
@@ -342,6 +364,31 @@ func Claims(r *http.Request) jwt.Claims {
 	return nil
 }
 
+// SubjectUser returns the subject of the claims stored in the request by
+// RequireToken, or an empty string. RequireToken records it as the user of
+// the request, via handler.SetUser, so that a wrapping log.Access handler
+// includes it in the access log. It also matches log.UserResolverFunc, for
+// when RequireToken wraps log.Access instead.
+func SubjectUser(r *http.Request) string {
+	switch claims := Claims(r).(type) {
+	case nil:
+		return ""
+	case *jwt.StandardClaims:
+		return claims.Subject
+	case jwt.MapClaims:
+		sub, _ := claims["sub"].(string)
+		return sub
+	default:
+		// Custom claims usually embed the standard ones
+		var std jwt.StandardClaims
+		if b, err := json.Marshal(claims); err == nil && json.Unmarshal(b, &std) == nil {
+			return std.Subject
+		}
+	}
+
+	return ""
+}
+
 func (o *options) apply(opts []TokenOpt) {
 	for _, op := range opts {
 		op.f(o)
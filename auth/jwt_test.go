@@ -1,6 +1,7 @@
 package auth_test
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
 	"github.com/urandom/handler/auth"
+	"github.com/urandom/handler/log"
 )
 
 var (
@@ -237,3 +239,88 @@ func validator(blacklisted string) auth.TokenValidator {
 		return false
 	})
 }
+
+type customClaims struct {
+	jwt.StandardClaims
+	Role string `json:"role"`
+}
+
+func TestSubjectUser(t *testing.T) {
+	cases := []struct {
+		claims jwt.Claims
+		user   string
+	}{
+		{nil, ""},
+		{&jwt.StandardClaims{Subject: "standard"}, "standard"},
+		{jwt.MapClaims{"sub": "map"}, "map"},
+		{jwt.MapClaims{"sub": 42}, ""},
+		{customClaims{StandardClaims: jwt.StandardClaims{Subject: "custom"}, Role: "admin"}, "custom"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+			if tc.claims != nil {
+				r = r.WithContext(context.WithValue(r.Context(), auth.ClaimsKey, tc.claims))
+			}
+
+			if user := auth.SubjectUser(r); user != tc.user {
+				t.Fatalf("expected user %q, got %q", tc.user, user)
+			}
+		})
+	}
+
+	var user string
+	h := auth.RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = auth.SubjectUser(r)
+	}), validator(""), secret)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	r.Header.Add("Authorization", "Bearer "+token(t, "user", time.Now().Add(time.Hour)))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if user != "user" {
+		t.Fatalf("expected user %q, got %q", "user", user)
+	}
+}
+
+type accessLogger struct {
+	message string
+}
+
+func (l *accessLogger) Print(v ...interface{}) {
+	l.message = fmt.Sprint(v...)
+}
+
+func TestRequireTokenAccessUser(t *testing.T) {
+	cases := []struct {
+		wrap func(h http.Handler, l *accessLogger) http.Handler
+		user string
+	}{
+		{func(h http.Handler, l *accessLogger) http.Handler {
+			return log.Access(auth.RequireToken(h, validator(""), secret), log.Logger(l), log.Format("%u"))
+		}, "user"},
+		{func(h http.Handler, l *accessLogger) http.Handler {
+			return auth.RequireToken(log.Access(h, log.Logger(l), log.Format("%u"),
+				log.RemoteUser(log.UserResolverFunc(auth.SubjectUser))), validator(""), secret)
+		}, "user"},
+		{func(h http.Handler, l *accessLogger) http.Handler {
+			return auth.RequireToken(log.Access(h, log.Logger(l), log.Format("%u")), validator(""), secret)
+		}, "-"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &accessLogger{}
+			h := tc.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), l)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+			r.Header.Add("Authorization", "Bearer "+token(t, "user", time.Now().Add(time.Hour)))
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if l.message != tc.user {
+				t.Fatalf("expected user %q, got %q", tc.user, l.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPResolver determines the IP address of the client that made a request.
+type IPResolver interface {
+	ClientIP(r *http.Request) string
+}
+
+// The IPResolverFunc type is an adapter to allow using ordinary functions as
+// IP resolvers.
+type IPResolverFunc func(r *http.Request) string
+
+func (f IPResolverFunc) ClientIP(r *http.Request) string {
+	return f(r)
+}
+
+// The forwarding headers that may carry the client address, as set by a
+// trusted proxy.
+const (
+	// ForwardedHeader is the RFC 7239 Forwarded header.
+	ForwardedHeader = "Forwarded"
+	// XForwardedForHeader is the de facto standard X-Forwarded-For header.
+	XForwardedForHeader = "X-Forwarded-For"
+	// XRealIPHeader is the X-Real-Ip header, holding a single address.
+	XRealIPHeader = "X-Real-Ip"
+)
+
+// TrustedProxies is an IPResolver that only takes a forwarding header into
+// account when the request arrives from a trusted proxy. Its zero value
+// trusts no proxies, and always returns the address of the connection peer.
+type TrustedProxies struct {
+	header   string
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies creates a new resolver, trusting the proxies in the given
+// networks to set the named forwarding header, which is one of
+// ForwardedHeader, XForwardedForHeader or XRealIPHeader. Only that header is
+// read, since the others may have been passed on unchanged from the client.
+// Each network is either in CIDR notation, such as '10.0.0.0/8', or a single
+// IP address.
+func NewTrustedProxies(header string, networks ...string) (*TrustedProxies, error) {
+	t := &TrustedProxies{header: http.CanonicalHeaderKey(header)}
+
+	switch t.header {
+	case ForwardedHeader, XForwardedForHeader, XRealIPHeader:
+	default:
+		return nil, fmt.Errorf("unsupported forwarding header %q", header)
+	}
+
+	for _, n := range networks {
+		if !strings.Contains(n, "/") {
+			if ip := net.ParseIP(n); ip != nil && ip.To4() != nil {
+				n += "/32"
+			} else {
+				n += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, err
+		}
+
+		t.networks = append(t.networks, network)
+	}
+
+	return t, nil
+}
+
+// ClientIP returns the IP address of the client. If the connection peer is a
+// trusted proxy, the address is taken from the forwarding header. With the
+// Forwarded and X-Forwarded-For headers, the chain of addresses is walked
+// from right to left, skipping over trusted proxies. The first untrusted
+// address is that of the client. If the chain is exhausted, its leftmost
+// address is returned. If it contains an invalid or obfuscated address, the
+// last valid one is returned instead. If the header is missing, or the
+// X-Real-Ip header is invalid, the address of the peer is returned.
+func (t *TrustedProxies) ClientIP(r *http.Request) string {
+	remote := hostOnly(r.RemoteAddr)
+
+	if !t.trusted(remote) {
+		return remote
+	}
+
+	var chain []string
+	switch t.header {
+	case ForwardedHeader:
+		chain = forwardedFor(r.Header)
+	case XForwardedForHeader:
+		chain = splitList(r.Header[XForwardedForHeader])
+	case XRealIPHeader:
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(XRealIPHeader))); ip != nil {
+			return ip.String()
+		}
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hostOnly(chain[i]))
+		if ip == nil {
+			break
+		}
+
+		client = ip.String()
+		if !t.trusted(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+func (t *TrustedProxies) trusted(addr string) bool {
+	if t == nil || len(t.networks) == 0 {
+		return false
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range t.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostOnly strips the port, and any IPv6 brackets, from the address.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// forwardedFor returns the 'for' parameters of all elements in the RFC 7239
+// Forwarded header. Elements without such a parameter yield an empty
+// string, which is treated as invalid.
+func forwardedFor(h http.Header) []string {
+	var chain []string
+	for _, element := range splitList(h[ForwardedHeader]) {
+		var forValue string
+		for _, pair := range strings.Split(element, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+				forValue = strings.Trim(kv[1], `"`)
+			}
+		}
+
+		chain = append(chain, forValue)
+	}
+
+	return chain
+}
+
+// splitList splits all comma-separated header values into a single list.
+func splitList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	xff, fwd, real := handler.XForwardedForHeader, handler.ForwardedHeader, handler.XRealIPHeader
+
+	cases := []struct {
+		header  string
+		trusted []string
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		{xff, nil, "1.2.3.4:5678", map[string]string{"X-Forwarded-For": "5.6.7.8"}, "1.2.3.4"},
+		{xff, []string{"10.0.0.0/8"}, "1.2.3.4:5678", map[string]string{"X-Forwarded-For": "5.6.7.8"}, "1.2.3.4"},
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Forwarded-For": "6.6.6.6, 5.6.7.8, 10.0.0.2"}, "5.6.7.8"},
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Forwarded-For": "garbage, 10.0.0.2"}, "10.0.0.2"},
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Real-Ip": "5.6.7.8"}, "10.0.0.1"},
+		// A client forging the Forwarded header behind a proxy that only
+		// appends to X-Forwarded-For
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{
+			"Forwarded":       "for=1.2.3.4",
+			"X-Forwarded-For": "5.6.7.8",
+		}, "5.6.7.8"},
+		{xff, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"Forwarded": "for=1.2.3.4"}, "10.0.0.1"},
+		{real, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Real-Ip": "5.6.7.8", "X-Forwarded-For": "1.2.3.4"}, "5.6.7.8"},
+		{real, []string{"10.0.0.0/8"}, "10.0.0.1:5678", map[string]string{"X-Real-Ip": "garbage"}, "10.0.0.1"},
+		{real, []string{"10.0.0.0/8"}, "1.2.3.4:5678", map[string]string{"X-Real-Ip": "5.6.7.8"}, "1.2.3.4"},
+		{fwd, []string{"10.0.0.1"}, "10.0.0.1:5678", map[string]string{
+			"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, For=10.0.0.1`,
+			"X-Forwarded-For": "7.7.7.7",
+		}, "2001:db8:cafe::17"},
+		{fwd, []string{"10.0.0.1"}, "10.0.0.1:5678", map[string]string{"X-Forwarded-For": "7.7.7.7"}, "10.0.0.1"},
+		{fwd, []string{"10.0.0.1"}, "10.0.0.1:5678", map[string]string{"Forwarded": `for=_hidden, for=5.6.7.8`}, "5.6.7.8"},
+		{fwd, []string{"10.0.0.0/8", "5.6.7.8"}, "10.0.0.1:5678", map[string]string{"Forwarded": `for=unknown, for=5.6.7.8`}, "5.6.7.8"},
+		{"x-forwarded-for", []string{"::1"}, "[::1]:5678", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+		{xff, nil, "1.2.3.4", nil, "1.2.3.4"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			resolver, err := handler.NewTrustedProxies(tc.header, tc.trusted...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.RemoteAddr = tc.remote
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+
+			if ip := resolver.ClientIP(r); ip != tc.ip {
+				t.Fatalf("expected ip %s, got %s", tc.ip, ip)
+			}
+		})
+	}
+
+	if _, err := handler.NewTrustedProxies(handler.XForwardedForHeader, "not an ip"); err == nil {
+		t.Fatalf("expected an error for an invalid network")
+	}
+
+	if _, err := handler.NewTrustedProxies("X-Client-Ip", "10.0.0.0/8"); err == nil {
+		t.Fatalf("expected an error for an unsupported header")
+	}
+}
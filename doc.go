@@ -4,8 +4,11 @@ Package handler is a collection of useful http.Handlers
 The following subpackages contain:
 
 	* log - handlers for logging requests and panics
-	* encoding - a handler for using gzip compression on the response
+	* encoding - handlers for compressing the response
 	* lang - a handler for setting up i18n urls
+	* metrics - a handler for recording request metrics
+	* session - implementations of the Session interface
+	* trace - handlers for correlating requests with logs and other services
 
 The package itself contains some common interfaces and useful types used by all
 handlers.
@@ -0,0 +1,66 @@
+package encoding
+
+import (
+	"net/http"
+
+	"github.com/urandom/handler"
+)
+
+// Preference sets the encodings used by the Compress handler, in the order
+// preferred by the server. By default, all registered encodings are used,
+// in the order they were registered.
+func Preference(encodings ...string) Option {
+	return Option{func(o *options) {
+		o.preference = encodings
+	}}
+}
+
+// Compress returns a handler that compresses the response body of handler h,
+// using the best encoding that is acceptable to the client. Out of the
+// registered encodings, the one with the highest quality value in the
+// request's 'Accept-Encoding' header is chosen, with ties broken by the
+// server preference. The response is sent uncompressed if the identity
+// coding has a higher quality value, either explicitly or via the '*'
+// wildcard, or if none of the encodings are acceptable. In the latter case,
+// if the client has explicitly excluded the identity coding, via
+// 'identity;q=0' or '*;q=0', a 406 Not Acceptable response is sent instead.
+// The header is parsed according to RFC 9110.
+//
+// Like Gzip, the body is compressed as it is being written, and the same
+// rules apply as to which responses are compressed.
+//
+// By default, no messages are printed out.
+func Compress(h http.Handler, opts ...Option) http.Handler {
+	o := options{
+		logger:        handler.NopLogger(),
+		preference:    Encoders(),
+		excludedTypes: DefaultExcludedContentTypes,
+	}
+	o.apply(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header())
+
+		name, ok := negotiate(r.Header["Accept-Encoding"], o.preference)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return
+		}
+
+		e, ok := lookup(name)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := newCompressWriter(w, r, o, e.Encoding(), o.newWriter(e))
+
+		h.ServeHTTP(cw, r)
+
+		if err := cw.Close(); err != nil {
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "compress handler", append(handler.RequestFields(r),
+				handler.Field{Key: "encoding", Value: name},
+				handler.Field{Key: "error", Value: err})...)
+		}
+	})
+}
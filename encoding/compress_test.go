@@ -0,0 +1,233 @@
+package encoding_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/urandom/handler/encoding"
+)
+
+func decode(t *testing.T, encoding string, r io.Reader) string {
+	var dec io.Reader
+	switch encoding {
+	case "":
+		dec = r
+	case "gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("gzip reader create: %s", err)
+		}
+		dec = gz
+	case "deflate":
+		dec = flate.NewReader(r)
+	case "br":
+		dec = brotli.NewReader(r)
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatalf("zstd reader create: %s", err)
+		}
+		defer zr.Close()
+		dec = zr
+	case "upper":
+		b, _ := ioutil.ReadAll(r)
+		return strings.ToLower(string(b))
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+
+	b, err := ioutil.ReadAll(dec)
+	if err != nil {
+		t.Fatalf("%s reader: %s", encoding, err)
+	}
+
+	return string(b)
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u upperWriter) Write(b []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(b))
+}
+
+func (u upperWriter) Flush() error { return nil }
+func (u upperWriter) Close() error { return nil }
+
+func TestCompress(t *testing.T) {
+	encoding.Register(encoding.NewEncoder("upper", func(w io.Writer) encoding.Writer {
+		return upperWriter{w}
+	}))
+
+	cases := []struct {
+		accept     string
+		preference []string
+		encoding   string
+	}{
+		{"", nil, ""},
+		{"gzip", nil, "gzip"},
+		{"deflate", nil, "deflate"},
+		{"br", nil, "br"},
+		{"zstd", nil, "zstd"},
+		{"gzip, deflate, br, zstd", nil, "br"},
+		{"gzip, deflate, br;q=0.5, zstd;q=0.9", nil, "gzip"},
+		{"gzip;q=0.5, deflate;q=0.8", nil, "deflate"},
+		{"GZIP", nil, "gzip"},
+		{"gzip, br", []string{"gzip", "br"}, "gzip"},
+		{"br", []string{"gzip"}, ""},
+		{"*", nil, "br"},
+		{"*, br;q=0", nil, "zstd"},
+		{"gzip;q=0", nil, ""},
+		{"compress", nil, ""},
+		{"upper", nil, "upper"},
+		{"upper, gzip", []string{"upper", "gzip"}, "upper"},
+	}
+
+	content := strings.Repeat("Test content ", 100)
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var opts []encoding.Option
+			if tc.preference != nil {
+				opts = append(opts, encoding.Preference(tc.preference...))
+			}
+
+			h := encoding.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			}), opts...)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept-Encoding", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, r)
+
+			if e := rec.Header().Get("Content-Encoding"); e != tc.encoding {
+				t.Fatalf("expected encoding %q, got %q", tc.encoding, e)
+			}
+
+			if v := rec.Header().Values("Vary"); len(v) != 1 || v[0] != "Accept-Encoding" {
+				t.Fatalf("expected a single Vary header, got %v", v)
+			}
+
+			if body := decode(t, tc.encoding, rec.Body); body != strings.ToLower(content) && body != content {
+				t.Fatalf("expected %s, got %s", content, body)
+			}
+		})
+	}
+}
+
+func TestCompressStreaming(t *testing.T) {
+	for _, name := range []string{"gzip", "deflate", "br", "zstd"} {
+		t.Run(name, func(t *testing.T) {
+			next := make(chan struct{})
+
+			s := httptest.NewServer(encoding.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; i < 2; i++ {
+					fmt.Fprintf(w, "data: %d\n\n", i)
+					w.(http.Flusher).Flush()
+
+					<-next
+				}
+			})))
+			defer s.Close()
+
+			r, _ := http.NewRequest("GET", s.URL, nil)
+			r.Header.Set("Accept-Encoding", name)
+
+			resp, err := (&http.Client{Transport: &http.Transport{DisableCompression: true}}).Do(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+
+			var dec io.Reader
+			switch name {
+			case "gzip":
+				dec, _ = gzip.NewReader(resp.Body)
+			case "deflate":
+				dec = flate.NewReader(resp.Body)
+			case "br":
+				dec = brotli.NewReader(resp.Body)
+			case "zstd":
+				zr, _ := zstd.NewReader(resp.Body)
+				defer zr.Close()
+				dec = zr
+			}
+
+			for i := 0; i < 2; i++ {
+				expected := fmt.Sprintf("data: %d\n\n", i)
+				b := make([]byte, len(expected))
+				if _, err := io.ReadFull(dec, b); err != nil {
+					t.Fatalf("%s reader: %s", name, err)
+				}
+
+				if string(b) != expected {
+					t.Fatalf("expected %q, got %q", expected, string(b))
+				}
+
+				next <- struct{}{}
+			}
+		})
+	}
+}
+
+func TestCompressWriteAfterClose(t *testing.T) {
+	content := strings.Repeat("Test content ", 100)
+
+	var leaked http.ResponseWriter
+	var lateErr error
+	h := encoding.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if leaked == nil {
+			leaked = w
+		} else {
+			// The pooled writer of the first response is now used by this
+			// one
+			_, lateErr = leaked.Write([]byte("late"))
+			leaked.(http.Flusher).Flush()
+		}
+
+		w.Write([]byte(content))
+	}), encoding.Preference("gzip"))
+
+	serve := func() *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, r)
+
+		return rec
+	}
+
+	first := serve()
+	firstLen := first.Body.Len()
+
+	second := serve()
+
+	if lateErr == nil {
+		t.Fatalf("expected an error when writing after the handler returned")
+	}
+
+	if first.Body.Len() != firstLen {
+		t.Fatalf("expected the first response to remain unchanged")
+	}
+
+	if body := decode(t, "gzip", second.Body); body != content {
+		t.Fatalf("expected %s, got %s", content, body)
+	}
+}
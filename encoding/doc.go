@@ -1,4 +1,5 @@
 /*
-Package encoding provides an handler that compresses the response body using gzip.
+Package encoding provides handlers that compress the response body as it is
+being written, using gzip, or any of the registered encodings.
 */
 package encoding
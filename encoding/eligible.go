@@ -0,0 +1,113 @@
+package encoding
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultExcludedContentTypes are the content types that are not compressed
+// by default, since they are already compressed.
+var DefaultExcludedContentTypes = []string{
+	"image/*",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/zstd",
+	"application/pdf",
+}
+
+// MinSize sets the minimum size of a response body, in bytes, for it to be
+// compressed. Smaller bodies are sent as they are, since compressing them
+// doesn't pay off. Unless the handler sets a Content-Length header, up to
+// size bytes of the body are buffered in order to decide. A response that is
+// flushed before that is compressed.
+func MinSize(size int) Option {
+	return Option{func(o *options) {
+		o.minSize = size
+	}}
+}
+
+// ContentTypes restricts compression to responses with one of the given
+// content types. A type may also be a pattern, such as 'text/*'.
+func ContentTypes(types ...string) Option {
+	return Option{func(o *options) {
+		o.contentTypes = types
+	}}
+}
+
+// ExcludeContentTypes prevents responses with any of the given content
+// types, or type patterns, from being compressed, replacing
+// DefaultExcludedContentTypes.
+func ExcludeContentTypes(types ...string) Option {
+	return Option{func(o *options) {
+		o.excludedTypes = types
+	}}
+}
+
+// eligible reports whether a response with the given status code and
+// header, to request r, should be compressed. Responses to HEAD requests,
+// responses without a body, ones that are already encoded, or declared to be
+// too small, and ones with excluded content types are not.
+func (o options) eligible(r *http.Request, code int, h http.Header) bool {
+	if r.Method == http.MethodHead || code < 200 || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	if cl := h.Get("Content-Length"); cl != "" && o.minSize > 0 {
+		if n, err := strconv.Atoi(cl); err == nil && n < o.minSize {
+			return false
+		}
+	}
+
+	ct := mediaType(h.Get("Content-Type"))
+
+	if len(o.contentTypes) > 0 && !matchType(o.contentTypes, ct) {
+		return false
+	}
+
+	return !matchType(o.excludedTypes, ct)
+}
+
+// mediaType returns the lowercased media type, without any parameters.
+func mediaType(ct string) string {
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+
+	if i := strings.IndexByte(ct, ';'); i != -1 {
+		ct = ct[:i]
+	}
+
+	return strings.ToLower(strings.TrimSpace(ct))
+}
+
+// matchType reports whether the media type matches any of the patterns,
+// which are either exact types, 'type/*' or '*/*'.
+func matchType(patterns []string, mt string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+
+		switch {
+		case p == mt, p == "*/*", p == "*":
+			return true
+		case strings.HasSuffix(p, "/*") && strings.HasPrefix(mt, p[:len(p)-1]):
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package encoding_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler/encoding"
+)
+
+func TestEligible(t *testing.T) {
+	content := strings.Repeat("Test content ", 100)
+
+	cases := []struct {
+		method        string
+		status        int
+		contentType   string
+		encoding      string
+		contentLength bool
+		body          string
+		opts          []encoding.Option
+		compressed    bool
+	}{
+		{body: content, compressed: true},
+		{body: "short", compressed: true},
+		{body: "short", opts: []encoding.Option{encoding.MinSize(100)}},
+		{body: content, opts: []encoding.Option{encoding.MinSize(100)}, compressed: true},
+		{body: content, opts: []encoding.Option{encoding.MinSize(len(content) + 1)}},
+		{body: content, contentLength: true, opts: []encoding.Option{encoding.MinSize(len(content) + 1)}},
+		{body: content, contentLength: true, opts: []encoding.Option{encoding.MinSize(100)}, compressed: true},
+		{method: "HEAD", body: content},
+		{status: http.StatusNoContent},
+		{status: http.StatusNotModified},
+		{status: http.StatusNotFound, body: content, compressed: true},
+		{contentType: "image/png", body: content},
+		{contentType: "video/mp4", body: content},
+		{contentType: "application/zip", body: content},
+		{contentType: "Application/PDF; name=doc.pdf", body: content},
+		{contentType: "image/svg+xml", body: content, opts: []encoding.Option{encoding.ExcludeContentTypes()}, compressed: true},
+		{contentType: "text/html; charset=utf-8", body: content, opts: []encoding.Option{encoding.ExcludeContentTypes("text/*")}},
+		{contentType: "application/json", body: content, opts: []encoding.Option{encoding.ContentTypes("text/*", "application/json")}, compressed: true},
+		{contentType: "text/css", body: content, opts: []encoding.Option{encoding.ContentTypes("text/*", "application/json")}, compressed: true},
+		{contentType: "application/wasm", body: content, opts: []encoding.Option{encoding.ContentTypes("text/*", "application/json")}},
+		{body: "\x89PNG\x0d\x0a\x1a\x0a" + content},
+		{encoding: "identity", body: content},
+		{encoding: "gzip", body: "already compressed"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			for _, name := range []string{"gzip", "compress"} {
+				h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tc.contentType != "" {
+						w.Header().Set("Content-Type", tc.contentType)
+					}
+					if tc.encoding != "" {
+						w.Header().Set("Content-Encoding", tc.encoding)
+					}
+					if tc.contentLength {
+						w.Header().Set("Content-Length", strconv.Itoa(len(tc.body)))
+					}
+					if tc.status != 0 {
+						w.WriteHeader(tc.status)
+					}
+
+					if tc.body != "" && r.Method != "HEAD" {
+						// Multiple writes, to exercise buffering
+						half := len(tc.body) / 2
+						w.Write([]byte(tc.body[:half]))
+						w.Write([]byte(tc.body[half:]))
+					}
+				})
+
+				var handler http.Handler
+				if name == "gzip" {
+					handler = encoding.Gzip(h, tc.opts...)
+				} else {
+					handler = encoding.Compress(h, append(tc.opts, encoding.Preference("gzip"))...)
+				}
+
+				method := tc.method
+				if method == "" {
+					method = "GET"
+				}
+
+				r, _ := http.NewRequest(method, "http://localhost:8080", nil)
+				r.Header.Set("Accept-Encoding", "gzip")
+				rec := httptest.NewRecorder()
+
+				handler.ServeHTTP(rec, r)
+
+				expectedStatus := tc.status
+				if expectedStatus == 0 {
+					expectedStatus = http.StatusOK
+				}
+				if rec.Code != expectedStatus {
+					t.Fatalf("%s: expected status %d, got %d", name, expectedStatus, rec.Code)
+				}
+
+				e := rec.Header().Get("Content-Encoding")
+				if tc.compressed {
+					if e != "gzip" {
+						t.Fatalf("%s: expected gzip encoding, got %q", name, e)
+					}
+
+					if rec.Header().Get("Content-Length") != "" {
+						t.Fatalf("%s: unexpected content length", name)
+					}
+
+					if body := decode(t, "gzip", rec.Body); body != tc.body {
+						t.Fatalf("%s: expected %q, got %q", name, tc.body, body)
+					}
+
+					continue
+				}
+
+				if e != tc.encoding {
+					t.Fatalf("%s: expected encoding %q, got %q", name, tc.encoding, e)
+				}
+
+				if tc.method != "HEAD" && rec.Body.String() != tc.body {
+					t.Fatalf("%s: expected %q, got %q", name, tc.body, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEligibleEmptyWrite(t *testing.T) {
+	for _, code := range []int{0, http.StatusOK, http.StatusNotFound} {
+		t.Run(fmt.Sprintf("code %d", code), func(t *testing.T) {
+			h := encoding.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if code != 0 {
+					w.WriteHeader(code)
+				}
+
+				w.Write(nil)
+				w.Write([]byte{})
+			}))
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, r)
+
+			expected := code
+			if expected == 0 {
+				expected = http.StatusOK
+			}
+
+			if rec.Code != expected {
+				t.Fatalf("expected code %d, got %d", expected, rec.Code)
+			}
+
+			if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+				t.Fatalf("expected an empty, unencoded response, got %v %q", rec.Header(), rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package encoding
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Writer is a compressing writer, produced by an Encoder.
+type Writer interface {
+	io.WriteCloser
+
+	// Flush writes any pending compressed data to the underlying writer.
+	Flush() error
+}
+
+// Encoder compresses response bodies using a single content coding.
+type Encoder interface {
+	// Encoding returns the name of the content coding, as used in the
+	// Accept-Encoding and Content-Encoding headers.
+	Encoding() string
+
+	// NewWriter returns a writer that compresses its input into w.
+	NewWriter(w io.Writer) Writer
+}
+
+// LevelEncoder is an Encoder that supports different compression levels,
+// as set by the Level option.
+type LevelEncoder interface {
+	Encoder
+
+	// NewWriterLevel returns a writer that compresses its input into w,
+	// using the given compression level.
+	NewWriterLevel(w io.Writer, level int) Writer
+}
+
+// NewEncoder returns an encoder for the named content coding, which uses
+// the function f to create its writers. Writers that implement a
+// Reset(io.Writer) method are pooled, and reused once closed. Such writers
+// must not be used after they are closed.
+func NewEncoder(name string, f func(w io.Writer) Writer) Encoder {
+	return &funcEncoder{name: name, f: func(w io.Writer, _ int) Writer {
+		return f(w)
+	}}
+}
+
+// NewLevelEncoder returns an encoder for the named content coding, which
+// uses the function f to create its writers with a given compression level,
+// or DefaultCompression. Like with NewEncoder, writers that implement a
+// Reset(io.Writer) method are pooled, separately for each level.
+func NewLevelEncoder(name string, f func(w io.Writer, level int) Writer) LevelEncoder {
+	return levelEncoder{&funcEncoder{name: name, f: f}}
+}
+
+type funcEncoder struct {
+	name  string
+	f     func(w io.Writer, level int) Writer
+	pools sync.Map
+}
+
+func (e *funcEncoder) Encoding() string {
+	return e.name
+}
+
+func (e *funcEncoder) NewWriter(w io.Writer) Writer {
+	return e.writer(w, DefaultCompression)
+}
+
+type levelEncoder struct {
+	*funcEncoder
+}
+
+func (e levelEncoder) NewWriterLevel(w io.Writer, level int) Writer {
+	return e.writer(w, level)
+}
+
+var (
+	// GzipEncoder compresses using the gzip content coding, with levels
+	// ranging from gzip.HuffmanOnly to gzip.BestCompression.
+	GzipEncoder LevelEncoder = NewLevelEncoder("gzip", func(w io.Writer, level int) Writer {
+		gw, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return gzip.NewWriter(w)
+		}
+		return gw
+	})
+
+	// DeflateEncoder compresses using the deflate content coding, with levels
+	// ranging from flate.HuffmanOnly to flate.BestCompression.
+	DeflateEncoder LevelEncoder = NewLevelEncoder("deflate", func(w io.Writer, level int) Writer {
+		fw, err := flate.NewWriter(w, level)
+		if err != nil {
+			fw, _ = flate.NewWriter(w, flate.DefaultCompression)
+		}
+		return fw
+	})
+
+	// BrotliEncoder compresses using the br content coding, with levels
+	// ranging from brotli.BestSpeed to brotli.BestCompression.
+	BrotliEncoder LevelEncoder = NewLevelEncoder("br", func(w io.Writer, level int) Writer {
+		if level < brotli.BestSpeed || level > brotli.BestCompression {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level)
+	})
+
+	// ZstdEncoder compresses using the zstd content coding, with levels
+	// ranging from 1 to 22, as used by the zstd command line tool.
+	ZstdEncoder LevelEncoder = NewLevelEncoder("zstd", func(w io.Writer, level int) Writer {
+		speed := zstd.SpeedDefault
+		if level > 0 {
+			speed = zstd.EncoderLevelFromZstd(level)
+		}
+
+		// Only fails for invalid options. A single goroutine per response
+		// is enough, and keeps flushes cheap
+		zw, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(speed))
+		return zw
+	})
+)
+
+var registry = struct {
+	sync.RWMutex
+	encoders map[string]Encoder
+	order    []string
+}{encoders: map[string]Encoder{}}
+
+func init() {
+	for _, e := range []Encoder{BrotliEncoder, ZstdEncoder, GzipEncoder, DeflateEncoder} {
+		Register(e)
+	}
+}
+
+// Register makes an encoder available to the Compress handler, replacing
+// any registered encoder for the same content coding. Unless the Preference
+// option is used, newly registered encodings are the least preferred ones.
+// The br, zstd, gzip and deflate encodings are registered by default, in
+// that order.
+func Register(e Encoder) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	name := e.Encoding()
+	if _, ok := registry.encoders[name]; !ok {
+		registry.order = append(registry.order, name)
+	}
+
+	registry.encoders[name] = e
+}
+
+// Encoders returns the names of all registered encodings, in their default
+// order of preference.
+func Encoders() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return append([]string(nil), registry.order...)
+}
+
+func lookup(name string) (Encoder, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	e, ok := registry.encoders[name]
+	return e, ok
+}
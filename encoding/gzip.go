@@ -1,15 +1,21 @@
 package encoding
 
 import (
-	"compress/gzip"
 	"net/http"
-	"strings"
 
 	"github.com/urandom/handler"
 )
 
 type options struct {
-	logger handler.Logger
+	logger     handler.Logger
+	slogger    handler.StructuredLogger
+	preference []string
+
+	minSize       int
+	contentTypes  []string
+	excludedTypes []string
+
+	levels map[string]int
 }
 
 // An Option is used to change the default behaviour of the encoding handlers.
@@ -25,50 +31,61 @@ func Logger(l handler.Logger) Option {
 	}}
 }
 
+// StructuredLogger defines a logger that will receive compression errors,
+// along with the request method, path and the used encoding. If set, it
+// takes precedence over the one provided by the Logger option.
+func StructuredLogger(l handler.StructuredLogger) Option {
+	return Option{func(o *options) {
+		o.slogger = l
+	}}
+}
+
 // Gzip returns a handler that will use gzip compression on the response body
 // of handler h. Compression will only be applied if the request contains an
-// 'Accept-Encoding' header that contains 'gzip'.
+// 'Accept-Encoding' header that accepts 'gzip', either by name or via the '*'
+// wildcard, with a non-zero quality value. Like Compress, it responds with
+// 406 Not Acceptable if the client accepts neither gzip nor an uncompressed
+// response.
+//
+// Responses to HEAD requests, responses without a body, or with a
+// Content-Encoding already set, are never compressed. The MinSize,
+// ContentTypes and ExcludeContentTypes options further limit which responses
+// get compressed. By default, content types that are already compressed,
+// listed in DefaultExcludedContentTypes, are skipped.
+//
+// The body is compressed as it is being written, without buffering the
+// whole response. Flushing the response writer flushes the compressed data
+// to the client, so that streamed responses, such as server-sent events,
+// remain live. The compression level may be set with the Level option, and
+// writers are reused between requests.
 //
 // By default, no messages are printed out.
 func Gzip(h http.Handler, opts ...Option) http.Handler {
-	o := options{logger: handler.OutLogger()}
+	o := options{logger: handler.OutLogger(), excludedTypes: DefaultExcludedContentTypes}
 	o.apply(opts)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			h.ServeHTTP(w, r)
-			return
-		}
-
-		wrapper := handler.NewResponseWrapper(w)
-
-		h.ServeHTTP(wrapper, r)
+		addVary(w.Header())
 
-		for k, v := range wrapper.Header() {
-			w.Header()[k] = v
+		name, ok := negotiate(r.Header["Accept-Encoding"], []string{"gzip"})
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return
 		}
-		w.Header().Set("Vary", "Accept-Encoding")
-		w.Header().Set("Content-Encoding", "gzip")
 
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", http.DetectContentType(wrapper.Body.Bytes()))
+		if name == "" {
+			h.ServeHTTP(w, r)
+			return
 		}
-		w.Header().Del("Content-Length")
 
-		if wrapper.Code != http.StatusOK {
-			w.WriteHeader(wrapper.Code)
-		}
+		cw := newCompressWriter(w, r, o, "gzip", o.newWriter(GzipEncoder))
 
-		gz := gzip.NewWriter(w)
-		gz.Flush()
+		h.ServeHTTP(cw, r)
 
-		if _, err := gz.Write(wrapper.Body.Bytes()); err != nil {
-			o.logger.Print("gzip handler: " + err.Error())
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
+		if err := cw.Close(); err != nil {
+			handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "gzip handler", append(handler.RequestFields(r),
+				handler.Field{Key: "error", Value: err})...)
 		}
-
-		gz.Close()
 	})
 }
 
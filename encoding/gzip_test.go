@@ -3,9 +3,11 @@ package encoding_test
 import (
 	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/urandom/handler/encoding"
@@ -75,3 +77,150 @@ func TestGzip(t *testing.T) {
 		})
 	}
 }
+
+func TestGzipStreaming(t *testing.T) {
+	next := make(chan struct{})
+
+	s := httptest.NewServer(encoding.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+
+			// The client has to receive the event before the next one is
+			// produced
+			<-next
+		}
+	})))
+	defer s.Close()
+
+	r, _ := http.NewRequest("GET", s.URL, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := (&http.Client{Transport: &http.Transport{DisableCompression: true}}).Do(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response, got %v", resp.Header)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip reader create: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		expected := fmt.Sprintf("data: %d\n\n", i)
+		b := make([]byte, len(expected))
+		if _, err := io.ReadFull(gz, b); err != nil {
+			t.Fatalf("gzip reader: %s", err)
+		}
+
+		if string(b) != expected {
+			t.Fatalf("expected %q, got %q", expected, string(b))
+		}
+
+		next <- struct{}{}
+	}
+
+	if rest, _ := ioutil.ReadAll(gz); len(rest) != 0 {
+		t.Fatalf("unexpected trailing data %q", string(rest))
+	}
+}
+
+func TestGzipNoBody(t *testing.T) {
+	h := encoding.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected code %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty, unencoded response, got %v %q", rec.Header(), rec.Body.String())
+	}
+}
+
+// sendfileRecorder records the use of io.ReaderFrom and http.Pusher.
+type sendfileRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+	pushed   []string
+}
+
+func (r *sendfileRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.readFrom = true
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+func (r *sendfileRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestGzipReadFrom(t *testing.T) {
+	content := strings.Repeat("Test content ", 100)
+
+	cases := []struct {
+		contentType string
+		compressed  bool
+	}{
+		{"text/plain", true},
+		{"image/png", false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			h := encoding.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := w.(http.Pusher).Push("/style.css", nil); err != nil {
+					t.Fatalf("push: %s", err)
+				}
+
+				w.Header().Set("Content-Type", tc.contentType)
+
+				n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader(content))
+				if err != nil || n != int64(len(content)) {
+					t.Fatalf("expected %d bytes read, got %d, %v", len(content), n, err)
+				}
+			}))
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := &sendfileRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+			h.ServeHTTP(rec, r)
+
+			if len(rec.pushed) != 1 || rec.pushed[0] != "/style.css" {
+				t.Fatalf("expected the push to be forwarded, got %v", rec.pushed)
+			}
+
+			if rec.readFrom == tc.compressed {
+				t.Fatalf("expected the wrapped ReadFrom to be used only for uncompressed responses")
+			}
+
+			expected := ""
+			if tc.compressed {
+				expected = "gzip"
+			}
+
+			if e := rec.Header().Get("Content-Encoding"); e != expected {
+				t.Fatalf("expected encoding %q, got %q", expected, e)
+			}
+
+			if body := decode(t, expected, rec.Body); body != content {
+				t.Fatalf("expected %s, got %s", content, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package encoding
+
+import (
+	"io"
+	"strings"
+)
+
+// DefaultCompression selects the default compression level of an encoder.
+const DefaultCompression = -1
+
+// Level sets the compression level used by the named encoding. The range of
+// valid levels depends on the encoder, with invalid ones replaced by the
+// default level. Levels are ignored by encoders that are not a LevelEncoder.
+func Level(encoding string, level int) Option {
+	return Option{func(o *options) {
+		if o.levels == nil {
+			o.levels = map[string]int{}
+		}
+
+		o.levels[strings.ToLower(encoding)] = level
+	}}
+}
+
+// newWriter returns the function that creates the writers of encoder e,
+// using the configured compression level, if any.
+func (o options) newWriter(e Encoder) func(w io.Writer) Writer {
+	if level, ok := o.levels[e.Encoding()]; ok {
+		if le, ok := e.(LevelEncoder); ok {
+			return func(w io.Writer) Writer {
+				return le.NewWriterLevel(w, level)
+			}
+		}
+	}
+
+	return e.NewWriter
+}
@@ -0,0 +1,88 @@
+package encoding_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler/encoding"
+)
+
+func TestLevel(t *testing.T) {
+	cases := []struct {
+		encoding string
+		level    int
+	}{
+		{"gzip", encoding.DefaultCompression},
+		{"gzip", gzip.HuffmanOnly},
+		{"gzip", gzip.BestSpeed},
+		{"gzip", gzip.BestCompression},
+		{"gzip", 42},
+		{"deflate", 1},
+		{"deflate", 9},
+		{"deflate", -42},
+		{"br", 0},
+		{"br", 11},
+		{"br", 42},
+		{"zstd", 1},
+		{"zstd", 22},
+		{"zstd", encoding.DefaultCompression},
+	}
+
+	content := strings.Repeat("Test content ", 100)
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			h := encoding.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			}), encoding.Level(tc.encoding, tc.level))
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.Header.Set("Accept-Encoding", tc.encoding)
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, r)
+
+			if e := rec.Header().Get("Content-Encoding"); e != tc.encoding {
+				t.Fatalf("expected encoding %q, got %q", tc.encoding, e)
+			}
+
+			if body := decode(t, tc.encoding, rec.Body); body != content {
+				t.Fatalf("expected %s, got %s", content, body)
+			}
+		})
+	}
+}
+
+func TestGzipLevel(t *testing.T) {
+	content := strings.Repeat("Test content ", 100)
+
+	size := func(opts ...encoding.Option) int {
+		h := encoding.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(content))
+		}), opts...)
+
+		r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, r)
+
+		return rec.Body.Len()
+	}
+
+	def := size()
+	huffman := size(encoding.Level("gzip", gzip.HuffmanOnly))
+	other := size(encoding.Level("br", gzip.HuffmanOnly))
+
+	if huffman <= def {
+		t.Fatalf("expected huffman only size %d to be larger than %d", huffman, def)
+	}
+
+	if other != def {
+		t.Fatalf("expected size %d, got %d", def, other)
+	}
+}
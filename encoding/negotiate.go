@@ -0,0 +1,120 @@
+package encoding
+
+import (
+	"strconv"
+	"strings"
+)
+
+// acceptedCoding is a single element of an Accept-Encoding header.
+type acceptedCoding struct {
+	name string
+	q    float64
+}
+
+// parseAcceptEncoding parses the codings and their quality values out of the
+// Accept-Encoding header values, as defined by RFC 9110, section 12.5.3.
+// Coding names are lowercased, with 'x-gzip' treated as 'gzip'. Elements
+// with invalid quality values are skipped.
+func parseAcceptEncoding(values []string) []acceptedCoding {
+	var codings []acceptedCoding
+
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			parts := strings.Split(element, ";")
+
+			name := strings.ToLower(strings.TrimSpace(parts[0]))
+			if name == "" {
+				continue
+			}
+
+			if name == "x-gzip" {
+				name = "gzip"
+			}
+
+			q, ok := 1.0, true
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "q") {
+					q, ok = parseQ(strings.TrimSpace(kv[1]))
+				}
+			}
+
+			if ok {
+				codings = append(codings, acceptedCoding{name, q})
+			}
+		}
+	}
+
+	return codings
+}
+
+// parseQ parses a quality value, which has to be between 0 and 1, with at
+// most three decimal digits.
+func parseQ(s string) (float64, bool) {
+	if len(s) == 0 || len(s) > 5 || s[0] != '0' && s[0] != '1' {
+		return 0, false
+	}
+
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, false
+	}
+
+	return q, true
+}
+
+// negotiate returns the encoding, out of the ones the server prefers, in
+// order, that has the highest quality in the Accept-Encoding header values.
+// Ties are broken by the server preference. It returns an empty string if
+// none of the encodings are acceptable, the identity coding has a higher
+// quality than all of them, or the request has no Accept-Encoding header. In that case, ok reports whether the identity
+// coding, meaning no compression, is acceptable instead.
+func negotiate(values []string, preference []string) (encoding string, ok bool) {
+	if len(values) == 0 {
+		// Any coding is acceptable, but identity is the safest choice
+		return "", true
+	}
+
+	codings := parseAcceptEncoding(values)
+
+	best, bestQ := "", 0.0
+	for _, name := range preference {
+		if q, _ := quality(codings, name); q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+
+	// Identity is always acceptable, unless explicitly excluded, either by
+	// name or by the wildcard
+	q, found := quality(codings, "identity")
+
+	if best != "" {
+		// An explicitly preferred identity wins, while a tie is resolved in
+		// favor of compression
+		if found && q > bestQ {
+			return "", true
+		}
+
+		return best, true
+	}
+
+	return "", !found || q > 0
+}
+
+// quality returns the quality value of the named coding, and whether it was
+// found in the header. An explicit entry takes precedence over the '*'
+// wildcard.
+func quality(codings []acceptedCoding, name string) (float64, bool) {
+	q, found := 0.0, false
+	for _, c := range codings {
+		if c.name == name {
+			return c.q, true
+		}
+
+		if c.name == "*" && !found {
+			q, found = c.q, true
+		}
+	}
+
+	return q, found
+}
@@ -0,0 +1,81 @@
+package encoding_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urandom/handler/encoding"
+)
+
+func TestAcceptEncoding(t *testing.T) {
+	cases := []struct {
+		accept   []string
+		gzipOnly bool
+		code     int
+		encoding string
+	}{
+		{nil, false, http.StatusOK, ""},
+		{[]string{""}, false, http.StatusOK, ""},
+		{[]string{"identity"}, false, http.StatusOK, ""},
+		{[]string{"identity;q=0"}, false, http.StatusNotAcceptable, ""},
+		{[]string{"*;q=0"}, false, http.StatusNotAcceptable, ""},
+		{[]string{"*;q=0, identity"}, false, http.StatusOK, ""},
+		{[]string{"gzip;q=0, identity;q=0"}, false, http.StatusNotAcceptable, ""},
+		{[]string{"gzip;q=0, *"}, false, http.StatusOK, "br"},
+		{[]string{"x-gzip"}, false, http.StatusOK, "gzip"},
+		{[]string{"gzip;q=1.5, deflate"}, false, http.StatusOK, "deflate"},
+		{[]string{"gzip;Q=0.2", "deflate;q=0.1"}, false, http.StatusOK, "gzip"},
+		{[]string{"gzip ; q=0.001"}, false, http.StatusOK, "gzip"},
+		{[]string{"gzip;q=0.0001"}, false, http.StatusOK, ""},
+		{[]string{"gzip;q=0"}, true, http.StatusOK, ""},
+		{[]string{"*"}, true, http.StatusOK, "gzip"},
+		{[]string{"gzip;q=0.5, *;q=0.9"}, true, http.StatusOK, ""},
+		{[]string{"gzip;q=0.9, *;q=0.5"}, true, http.StatusOK, "gzip"},
+		{[]string{"gzip;q=0.1, identity;q=1"}, true, http.StatusOK, ""},
+		{[]string{"gzip;q=0.1, identity;q=1"}, false, http.StatusOK, ""},
+		{[]string{"gzip;q=0.5, br;q=0.8, identity;q=0.6"}, false, http.StatusOK, "br"},
+		{[]string{"gzip, identity"}, false, http.StatusOK, "gzip"},
+		{[]string{"gzip;q=0.1"}, false, http.StatusOK, "gzip"},
+		{[]string{"br, identity;q=0"}, true, http.StatusNotAcceptable, ""},
+		{[]string{"deflate, gzip"}, true, http.StatusOK, "gzip"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Test content"))
+			})
+
+			var hh http.Handler
+			if tc.gzipOnly {
+				hh = encoding.Gzip(h)
+			} else {
+				hh = encoding.Compress(h)
+			}
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			if tc.accept != nil {
+				r.Header["Accept-Encoding"] = tc.accept
+			}
+			rec := httptest.NewRecorder()
+
+			hh.ServeHTTP(rec, r)
+
+			if rec.Code != tc.code {
+				t.Fatalf("expected code %d, got %d", tc.code, rec.Code)
+			}
+
+			if e := rec.Header().Get("Content-Encoding"); e != tc.encoding {
+				t.Fatalf("expected encoding %q, got %q", tc.encoding, e)
+			}
+
+			if tc.code == http.StatusOK {
+				if body := decode(t, tc.encoding, rec.Body); body != "Test content" {
+					t.Fatalf("expected %s, got %s", "Test content", body)
+				}
+			}
+		})
+	}
+}
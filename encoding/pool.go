@@ -0,0 +1,64 @@
+package encoding
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// resetter is implemented by writers that can be reused for a new
+// destination, such as the ones of the compress/gzip and compress/flate
+// packages.
+type resetter interface {
+	Reset(w io.Writer)
+}
+
+// writer returns a writer for the given level, reusing a closed one, if
+// available.
+func (e *funcEncoder) writer(w io.Writer, level int) Writer {
+	pool := e.pool(level)
+
+	if pw, ok := pool.Get().(*pooledWriter); ok {
+		pw.Reset(w)
+		return pw
+	}
+
+	zw := e.f(w, level)
+	if _, ok := zw.(resetter); !ok {
+		return zw
+	}
+
+	return &pooledWriter{Writer: zw, pool: pool}
+}
+
+func (e *funcEncoder) pool(level int) *sync.Pool {
+	if p, ok := e.pools.Load(level); ok {
+		return p.(*sync.Pool)
+	}
+
+	p, _ := e.pools.LoadOrStore(level, &sync.Pool{})
+	return p.(*sync.Pool)
+}
+
+// pooledWriter returns the writer it wraps to its pool, once closed.
+type pooledWriter struct {
+	Writer
+
+	pool *sync.Pool
+}
+
+func (w *pooledWriter) Reset(dst io.Writer) {
+	w.Writer.(resetter).Reset(dst)
+}
+
+// Close finishes the compressed stream, after which the writer may be
+// handed out again, and must no longer be used.
+func (w *pooledWriter) Close() error {
+	err := w.Writer.Close()
+
+	// Don't keep the last destination alive while pooled
+	w.Reset(ioutil.Discard)
+	w.pool.Put(w)
+
+	return err
+}
@@ -0,0 +1,120 @@
+package encoding_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/urandom/handler/encoding"
+)
+
+var benchmarkEncoders = []struct {
+	encoder encoding.Encoder
+	fresh   func(w io.Writer) encoding.Writer
+}{
+	{encoding.GzipEncoder, func(w io.Writer) encoding.Writer { return gzip.NewWriter(w) }},
+	{encoding.DeflateEncoder, func(w io.Writer) encoding.Writer {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}},
+	{encoding.BrotliEncoder, func(w io.Writer) encoding.Writer { return brotli.NewWriter(w) }},
+	{encoding.ZstdEncoder, func(w io.Writer) encoding.Writer {
+		zw, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return zw
+	}},
+}
+
+func TestPooledWriters(t *testing.T) {
+	for _, tc := range benchmarkEncoders {
+		name := tc.encoder.Encoding()
+
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					for j := 0; j < 10; j++ {
+						content := strings.Repeat(fmt.Sprintf("content %d-%d ", i, j), 50)
+
+						var buf bytes.Buffer
+						w := tc.encoder.NewWriter(&buf)
+						w.Write([]byte(content))
+						if err := w.Close(); err != nil {
+							t.Errorf("unexpected error: %s", err)
+							return
+						}
+
+						if body := decode(t, name, &buf); body != content {
+							t.Errorf("expected %q, got %q", content, body)
+							return
+						}
+					}
+				}(i)
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func BenchmarkEncoders(b *testing.B) {
+	content := []byte(strings.Repeat("Test content ", 1000))
+
+	for _, tc := range benchmarkEncoders {
+		name := tc.encoder.Encoding()
+
+		b.Run(name+"/pooled", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := tc.encoder.NewWriter(ioutil.Discard)
+				w.Write(content)
+				w.Close()
+			}
+		})
+
+		b.Run(name+"/fresh", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := tc.fresh(ioutil.Discard)
+				w.Write(content)
+				w.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	content := []byte(strings.Repeat("Test content ", 1000))
+
+	h := encoding.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+
+	for _, tc := range benchmarkEncoders {
+		name := tc.encoder.Encoding()
+
+		b.Run(name, func(b *testing.B) {
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.Header.Set("Accept-Encoding", name)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(httptest.NewRecorder(), r)
+			}
+		})
+	}
+}
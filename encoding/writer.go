@@ -0,0 +1,278 @@
+package encoding
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// compressWriter compresses the response body as it is being written.
+// Whether a response is compressed is decided with the first write or flush,
+// or once enough of the body has been buffered to satisfy the minimum size,
+// at which point the header is sent to the client. Responses without a body
+// are sent uncompressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	r         *http.Request
+	o         options
+	encoding  string
+	newWriter func(w io.Writer) Writer
+
+	enc      Writer
+	buf      []byte
+	code     int
+	decided  bool
+	hijacked bool
+	closed   bool
+	err      error
+}
+
+// errClosed is returned when writing to a response whose handler has already
+// returned.
+var errClosed = errors.New("encoding: write after the handler returned")
+
+func newCompressWriter(w http.ResponseWriter, r *http.Request, o options, encoding string, newWriter func(w io.Writer) Writer) *compressWriter {
+	return &compressWriter{ResponseWriter: w, r: r, o: o, encoding: encoding, newWriter: newWriter}
+}
+
+// WriteHeader records the status code. It is sent to the client once the
+// encoding of the body has been decided.
+func (w *compressWriter) WriteHeader(code int) {
+	if w.code != 0 || w.decided {
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.code = code
+}
+
+// Write compresses b, if the response is eligible for compression, sending
+// the header first if necessary.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, errClosed
+	}
+
+	if !w.decided && len(b) == 0 {
+		// Leave an empty body to Close, so that it is sent uncompressed
+		return 0, nil
+	}
+
+	if !w.decided {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+
+		if !w.o.eligible(w.r, w.code, w.header(b)) {
+			w.decide(false)
+		} else if len(w.buf)+len(b) < w.o.minSize {
+			w.buf = append(w.buf, b...)
+			return len(b), nil
+		} else {
+			w.decide(true)
+		}
+	}
+
+	if w.enc == nil {
+		return w.ResponseWriter.Write(b)
+	}
+
+	n, err := w.enc.Write(b)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	return n, err
+}
+
+// Flush writes any pending compressed data to the client, and flushes the
+// wrapped writer, if it supports flushing.
+func (w *compressWriter) Flush() {
+	if w.closed {
+		return
+	}
+
+	if !w.decided {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+
+		w.decide(w.o.eligible(w.r, w.code, w.header(nil)))
+	}
+
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil && w.err == nil {
+			w.err = err
+		}
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ReadFrom copies the contents of r to the response. Once the response is
+// known not to be compressed, the io.ReaderFrom implementation of the
+// wrapped writer is used, if available, so that sendfile keeps working.
+func (w *compressWriter) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+
+	// Feed the start of the body through Write, until the encoding is
+	// decided
+	buf := make([]byte, 512)
+	for !w.decided {
+		m, err := r.Read(buf)
+		if m > 0 {
+			if _, werr := w.Write(buf[:m]); werr != nil {
+				return n, werr
+			}
+			n += int64(m)
+		}
+
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+	}
+
+	if w.enc == nil {
+		if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+			m, err := rf.ReadFrom(r)
+			return n + m, err
+		}
+	}
+
+	// Hide ReadFrom from io.Copy, which would otherwise recurse
+	m, err := io.Copy(struct{ io.Writer }{w}, r)
+	return n + m, err
+}
+
+// Push initiates an HTTP/2 server push, if the wrapped writer is an
+// http.Pusher. Otherwise, http.ErrNotSupported is returned.
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Hijack tries to use the wrapped writer for hijacking.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		c, rw, err := hijacker.Hijack()
+		if err == nil {
+			w.hijacked = true
+		}
+
+		return c, rw, err
+	}
+
+	return nil, nil, errors.New("Wrapped ResponseWriter is not a Hijacker")
+}
+
+// Unwrap returns the wrapped writer, for use with http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close finishes the compressed stream. Bodies that are smaller than the
+// minimum size are written out uncompressed. It returns the first error
+// encountered while compressing. Any later writes fail, and flushes are
+// ignored.
+func (w *compressWriter) Close() error {
+	if w.hijacked || w.closed {
+		return w.err
+	}
+
+	w.closed = true
+
+	if !w.decided {
+		if w.code == 0 && len(w.buf) == 0 {
+			// Nothing was written, let the server respond as usual
+			return nil
+		}
+
+		w.decide(false)
+	}
+
+	if w.enc != nil {
+		if err := w.enc.Close(); err != nil && w.err == nil {
+			w.err = err
+		}
+
+		// The encoder may be handed out to another response now
+		w.enc = nil
+	}
+
+	return w.err
+}
+
+// header returns the response header, with the content type detected from
+// the buffered body, or b, if not set.
+func (w *compressWriter) header(b []byte) http.Header {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		if len(w.buf) > 0 {
+			b = w.buf
+		}
+
+		if len(b) > 0 {
+			h.Set("Content-Type", http.DetectContentType(b))
+		}
+	}
+
+	return h
+}
+
+// decide sends the header, either for a compressed or an uncompressed body,
+// followed by any buffered data.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+
+	if compress {
+		h := w.Header()
+		addVary(h)
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+
+	if compress {
+		w.enc = w.newWriter(w.ResponseWriter)
+	}
+
+	if len(w.buf) > 0 {
+		buf := w.buf
+		w.buf = nil
+
+		if w.enc != nil {
+			_, w.err = w.enc.Write(buf)
+		} else {
+			_, w.err = w.ResponseWriter.Write(buf)
+		}
+	}
+}
+
+// addVary adds Accept-Encoding to the Vary header, unless already present.
+func addVary(h http.Header) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "Accept-Encoding") {
+				return
+			}
+		}
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+}
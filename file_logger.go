@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is used to name rotated log files. It sorts
+// chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type fileOptions struct {
+	maxSize    int64
+	daily      bool
+	maxBackups int
+	compress   bool
+	signals    []os.Signal
+}
+
+// A FileOption is used to change the default behaviour of the FileLogger.
+type FileOption struct {
+	f func(o *fileOptions)
+}
+
+var (
+	// RotateDaily causes the log file to be rotated on the first write of
+	// each day.
+	RotateDaily = FileOption{func(o *fileOptions) {
+		o.daily = true
+	}}
+
+	// CompressBackups causes rotated log files to be compressed using gzip.
+	CompressBackups = FileOption{func(o *fileOptions) {
+		o.compress = true
+	}}
+)
+
+// RotateSize causes the log file to be rotated before it grows beyond the
+// given size in bytes.
+func RotateSize(size int64) FileOption {
+	return FileOption{func(o *fileOptions) {
+		o.maxSize = size
+	}}
+}
+
+// MaxBackups sets the number of rotated log files to keep. Older ones are
+// removed. A value of 0 keeps all of them.
+func MaxBackups(n int) FileOption {
+	return FileOption{func(o *fileOptions) {
+		o.maxBackups = n
+	}}
+}
+
+// ReopenOn causes the log file to be reopened whenever one of the given
+// signals is received. Using syscall.SIGHUP makes the logger compatible with
+// external tools, such as logrotate, which move the file away.
+func ReopenOn(signals ...os.Signal) FileOption {
+	return FileOption{func(o *fileOptions) {
+		o.signals = signals
+	}}
+}
+
+// FileLogger is a Logger that appends its messages, one per line, to a file.
+// The file may be rotated by size or daily, with the rotated files
+// optionally compressed. It is safe for concurrent use.
+type FileLogger struct {
+	mu     sync.Mutex
+	path   string
+	o      fileOptions
+	file   *os.File
+	size   int64
+	day    string
+	closed bool
+
+	signals chan os.Signal
+	done    chan struct{}
+
+	// backupMu serializes the compression and removal of rotated files.
+	backupMu sync.Mutex
+	backups  sync.WaitGroup
+}
+
+// NewFileLogger creates a new logger that appends to the file at path,
+// creating it if necessary.
+func NewFileLogger(path string, opts ...FileOption) (*FileLogger, error) {
+	o := fileOptions{}
+	for _, op := range opts {
+		op.f(&o)
+	}
+
+	l := &FileLogger{path: path, o: o, done: make(chan struct{})}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	if len(o.signals) > 0 {
+		l.signals = make(chan os.Signal, 1)
+		signal.Notify(l.signals, o.signals...)
+
+		go l.watchSignals()
+	}
+
+	return l, nil
+}
+
+// Print writes the message to the file, followed by a new line. Any errors
+// are reported to os.Stderr, since there is nowhere else to send them.
+func (l *FileLogger) Print(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+
+	if _, err := l.Write([]byte(msg)); err != nil {
+		fmt.Fprintln(os.Stderr, "file logger:", err)
+	}
+}
+
+// PrintBatch writes all messages to the file with a single write, each one
+// followed by a new line.
+func (l *FileLogger) PrintBatch(messages []string) {
+	var buf strings.Builder
+	for _, msg := range messages {
+		buf.WriteString(msg)
+		if !strings.HasSuffix(msg, "\n") {
+			buf.WriteByte('\n')
+		}
+	}
+
+	if _, err := l.Write([]byte(buf.String())); err != nil {
+		fmt.Fprintln(os.Stderr, "file logger:", err)
+	}
+}
+
+// Write writes p to the file as is, rotating it first if necessary.
+func (l *FileLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, os.ErrClosed
+	}
+
+	if l.shouldRotate(int64(len(p)), time.Now()) {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+
+	return n, err
+}
+
+// Rotate renames the current file using the current time as a suffix, and
+// opens a new one in its place.
+func (l *FileLogger) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return os.ErrClosed
+	}
+
+	return l.rotate()
+}
+
+// Reopen closes and reopens the file at the logger's path. It should be used
+// after the file has been moved away by an external tool.
+func (l *FileLogger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return os.ErrClosed
+	}
+
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+// Close closes the file, and waits for any pending compression of rotated
+// files to finish.
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+
+	l.closed = true
+	if l.signals != nil {
+		signal.Stop(l.signals)
+	}
+	close(l.done)
+
+	err := l.file.Close()
+	l.mu.Unlock()
+
+	l.backups.Wait()
+
+	return err
+}
+
+func (l *FileLogger) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = fi.Size()
+	l.day = time.Now().Format("2006-01-02")
+
+	if l.size > 0 {
+		// An existing file belongs to the day it was last written to
+		l.day = fi.ModTime().Format("2006-01-02")
+	}
+
+	return nil
+}
+
+func (l *FileLogger) shouldRotate(n int64, now time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+
+	if l.o.maxSize > 0 && l.size+n > l.o.maxSize {
+		return true
+	}
+
+	return l.o.daily && now.Format("2006-01-02") != l.day
+}
+
+func (l *FileLogger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	backup := backupName(l.path, time.Now())
+	if err := os.Rename(l.path, backup); err != nil && !os.IsNotExist(err) {
+		// Keep writing to the old file rather than losing messages
+		if openErr := l.open(); openErr != nil {
+			return openErr
+		}
+
+		return err
+	}
+
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	l.backups.Add(1)
+	go l.processBackup(backup)
+
+	return nil
+}
+
+// processBackup compresses a rotated file, if requested, and removes the
+// oldest backups beyond the configured limit.
+func (l *FileLogger) processBackup(backup string) {
+	defer l.backups.Done()
+
+	l.backupMu.Lock()
+	defer l.backupMu.Unlock()
+
+	if l.o.compress {
+		// The backup may have already been pruned while processing a newer one
+		if err := compressFile(backup); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "file logger:", err)
+		}
+	}
+
+	if l.o.maxBackups <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return
+	}
+
+	var backups []backupFile
+	for _, m := range matches {
+		if b, ok := parseBackup(l.path, m); ok {
+			backups = append(backups, b)
+		}
+	}
+
+	// Backups rotated within the same millisecond only differ by their
+	// sequence number, which doesn't sort lexically against a compression
+	// suffix
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.Before(backups[j].time)
+		}
+
+		return backups[i].seq < backups[j].seq
+	})
+
+	for len(backups) > l.o.maxBackups {
+		if err := os.Remove(backups[0].name); err != nil {
+			fmt.Fprintln(os.Stderr, "file logger:", err)
+		}
+		backups = backups[1:]
+	}
+}
+
+func (l *FileLogger) watchSignals() {
+	for {
+		select {
+		case <-l.signals:
+			if err := l.Reopen(); err != nil {
+				fmt.Fprintln(os.Stderr, "file logger:", err)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// backupName returns an unused name for a rotated file.
+func backupName(path string, now time.Time) string {
+	base := path + "." + now.Format(backupTimeFormat)
+	name := base
+
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	return name
+}
+
+// backupFile is a rotated file, named by backupName, and possibly compressed.
+type backupFile struct {
+	name string
+	time time.Time
+	seq  int
+}
+
+// parseBackup parses the time and sequence number out of the name of a
+// backup of the file at path.
+func parseBackup(path, name string) (backupFile, bool) {
+	suffix := strings.TrimSuffix(name[len(path)+1:], ".gz")
+	if len(suffix) < len(backupTimeFormat) {
+		return backupFile{}, false
+	}
+
+	t, err := time.Parse(backupTimeFormat, suffix[:len(backupTimeFormat)])
+	if err != nil {
+		return backupFile{}, false
+	}
+
+	b := backupFile{name: name, time: t}
+
+	if rest := suffix[len(backupTimeFormat):]; rest != "" {
+		if !strings.HasPrefix(rest, "-") {
+			return backupFile{}, false
+		}
+
+		if b.seq, err = strconv.Atoi(rest[1:]); err != nil {
+			return backupFile{}, false
+		}
+	}
+
+	return b, true
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
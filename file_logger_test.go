@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+func TestFileLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	l, err := handler.NewFileLogger(path, handler.RotateSize(11), handler.MaxBackups(2), handler.CompressBackups)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Print("0123456789")
+		}()
+	}
+	wg.Wait()
+
+	l.Print("last")
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+
+	if string(b) != "last\n" {
+		t.Fatalf("expected the current file to contain %q, got %q", "last\n", string(b))
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	sort.Strings(backups)
+
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Fatalf("expected a compressed backup, got %s", backup)
+		}
+
+		f, _ := os.Open(backup)
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip reader create: %s", err)
+		}
+
+		content, _ := ioutil.ReadAll(gz)
+		f.Close()
+
+		if string(content) != "0123456789\n" {
+			t.Fatalf("unexpected backup content %q", string(content))
+		}
+	}
+}
+
+func TestFileLoggerBackupOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+
+	// Two backups rotated within the same millisecond, the second one
+	// getting a sequence number
+	older := path + ".2020-01-02T03-04-05.006.gz"
+	newer := path + ".2020-01-02T03-04-05.006-1.gz"
+	for _, name := range []string{older, newer} {
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatalf("write: %s", err)
+		}
+	}
+
+	l, err := handler.NewFileLogger(path, handler.RotateSize(11), handler.MaxBackups(2), handler.CompressBackups)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	l.Print("0123456789")
+	l.Print("0123456789")
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	sort.Strings(backups)
+
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+
+	if backups[0] != newer {
+		t.Fatalf("expected %s to be kept, got %v", newer, backups)
+	}
+}
+
+func TestFileLoggerReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	l, err := handler.NewFileLogger(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer l.Close()
+
+	l.Print("first")
+
+	// Simulate logrotate moving the file away
+	os.Rename(path, path+".1")
+
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("reopen: %s", err)
+	}
+
+	l.Print("second")
+
+	if b, _ := ioutil.ReadFile(path + ".1"); string(b) != "first\n" {
+		t.Fatalf("unexpected moved file content %q", string(b))
+	}
+
+	if b, _ := ioutil.ReadFile(path); string(b) != "second\n" {
+		t.Fatalf("unexpected new file content %q", string(b))
+	}
+}
@@ -17,6 +17,7 @@ type options struct {
 	session   handler.Session
 	urlPrefix string
 	logger    handler.Logger
+	slogger   handler.StructuredLogger
 }
 
 // An Option is used to change the default behaviour of the language handlers.
@@ -55,6 +56,16 @@ func Logger(l handler.Logger) Option {
 	}}
 }
 
+// StructuredLogger defines a logger that will receive errors from storing
+// the current language in the session, along with the request method and
+// path. If set, it takes precedence over the one provided by the Logger
+// option.
+func StructuredLogger(l handler.StructuredLogger) Option {
+	return Option{func(o *options) {
+		o.slogger = l
+	}}
+}
+
 type contextKey string
 
 // ContextValue is stored in the request context
@@ -154,7 +165,7 @@ func I18N(h http.Handler, opts ...Option) http.Handler {
 			url := o.urlPrefix
 
 			if c == xlang.No {
-				tag, _, _ = matcher.Match(fallbackLanguage(o.session, r))
+				tag, _, _ = matcher.Match(fallbackLanguage(o, r))
 				url += tag.String() + "/" + sub
 			} else {
 				url += tag.String() + "/" + sub[slashIndex+1:]
@@ -191,7 +202,8 @@ func I18N(h http.Handler, opts ...Option) http.Handler {
 
 			if o.session != nil {
 				if err := o.session.Set(r, SessionKey, tag.String()); err != nil {
-					o.logger.Print("i18n handler: " + err.Error())
+					handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "i18n handler", append(handler.RequestFields(r),
+						handler.Field{Key: "error", Value: err})...)
 				}
 			}
 
@@ -232,10 +244,18 @@ func URL(url, prefix string, data ContextValue) string {
 	return prefix + data.Current.String() + url
 }
 
-func fallbackLanguage(sess handler.Session, r *http.Request) xlang.Tag {
-	if sess != nil {
-		if val, err := sess.Get(r, SessionKey); err == nil && val != "" {
-			return xlang.Make(val)
+func fallbackLanguage(o options, r *http.Request) xlang.Tag {
+	if o.session != nil {
+		if val, err := o.session.Get(r, SessionKey); err == nil && val != "" {
+			if tag, err := xlang.Parse(val); err == nil {
+				return tag
+			}
+
+			// Remove the malformed language, so it isn't checked again
+			if err := handler.DeleteSessionValue(o.session, r, SessionKey); err != nil {
+				handler.Structured(o.slogger, o.logger).Log(handler.LevelError, "i18n handler", append(handler.RequestFields(r),
+					handler.Field{Key: "error", Value: err})...)
+			}
 		}
 	}
 
@@ -1,13 +1,11 @@
 package log
 
 import (
-	"encoding/base64"
-	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/urandom/handler"
+	"github.com/urandom/handler/trace"
 )
 
 // AccessDateFormat is the default timestamp format for the access log messages.
@@ -15,8 +13,25 @@ const AccessDateFormat = "Jan 2, 2006 at 3:04pm (MST)"
 
 type options struct {
 	logger     handler.Logger
+	slogger    handler.StructuredLogger
 	dateFormat string
 	showStack  bool
+	renderer   ErrorRenderer
+
+	hooks         []PanicHook
+	redactHeaders []string
+	format        accessFormatter
+	ipResolver    handler.IPResolver
+	users         []UserResolver
+
+	requestHeaders  []string
+	responseHeaders []string
+
+	slowerThan time.Duration
+	minStatus  int
+	sampleRate float64
+	sampled    bool
+	alwaysLog  []string
 }
 
 // An Option is used to change the default behaviour of logging handlers.
@@ -32,6 +47,25 @@ func Logger(l handler.Logger) Option {
 	}}
 }
 
+// StructuredLogger defines a logger that will receive leveled messages with
+// key/value fields, instead of preformatted lines. If set, it takes
+// precedence over the one provided by the Logger option.
+func StructuredLogger(l handler.StructuredLogger) Option {
+	return Option{func(o *options) {
+		o.slogger = l
+	}}
+}
+
+// IPResolver defines how the client IP address is obtained from a request.
+// By default, the address of the connection peer is used, ignoring any
+// forwarding headers. Use handler.NewTrustedProxies to take them into
+// account when behind a proxy.
+func IPResolver(r handler.IPResolver) Option {
+	return Option{func(o *options) {
+		o.ipResolver = r
+	}}
+}
+
 // DateFormat is used to format the timestamp.
 func DateFormat(f string) Option {
 	return Option{func(o *options) {
@@ -43,87 +77,110 @@ func DateFormat(f string) Option {
 // logger, provided by the options, whenever the handler h is invoked. The log
 // message is of the following format:
 //
-// IP - USER [DATETIME - DURATION] "HTTP_METHOD URI" STATUS_CODE BODY_LENGTH "REFERER" USER_AGENT
+// IP - USER [DATETIME - DURATION] "HTTP_METHOD URI" STATUS_CODE BODY_LENGTH "REFERER" USER_AGENT [REQUEST_ID]
+//
+// The request ID is only present if the handler is wrapped by the
+// trace.RequestID handler. Similarly, when wrapped by the trace.Trace
+// handler, the JSON and structured output include the trace and span IDs.
+//
+// The CommonLogFormat and CombinedLogFormat options may be used to produce
+// messages that are understood by standard log analyzers instead, while the
+// Format option allows defining a custom message format. JSONFormat produces
+// JSON lines, suitable for log shippers.
+//
+// If a StructuredLogger is provided, the message fields are passed to it
+// instead, with 5xx responses logged as errors, and 4xx ones as warnings.
+//
+// The SlowerThan, MinStatus, SampleRate and AlwaysLog options reduce the
+// number of logged requests, for use under high volume.
 //
 // By default, all messages are printed to os.Stdout.
 func Access(h http.Handler, opts ...Option) http.Handler {
-	o := options{logger: handler.OutLogger(), dateFormat: AccessDateFormat}
+	o := options{
+		logger:     handler.OutLogger(),
+		dateFormat: AccessDateFormat,
+		format:     defaultFormat,
+		ipResolver: &handler.TrustedProxies{},
+		users:      []UserResolver{BasicAuthUser},
+	}
 	o.apply(opts)
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		wrapper := handler.NewResponseWrapper(w)
+	filter := newAccessFilter(o)
 
-		uri := r.URL.RequestURI()
-		remoteAddr := remoteAddr(r)
-		remoteUser := remoteUser(r)
-		method := r.Method
-		referer := r.Header.Get("Referer")
-		userAgent := r.Header.Get("User-Agent")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = handler.WithNotes(r)
+
+		e := accessEntry{
+			start:      time.Now(),
+			remoteAddr: o.ipResolver.ClientIP(r),
+			requestID:  trace.RequestIDFrom(r.Context()),
+			method:     r.Method,
+			uri:        r.URL.RequestURI(),
+			path:       r.URL.Path,
+			query:      r.URL.RawQuery,
+			proto:      r.Proto,
+			referer:    r.Header.Get("Referer"),
+			userAgent:  r.Header.Get("User-Agent"),
+			fields:     handler.RequestFields(r),
+			request:    r,
+		}
 
-		h.ServeHTTP(wrapper, r)
+		if sc, ok := trace.SpanContextFrom(r.Context()); ok {
+			e.traceID = sc.TraceID.String()
+			e.spanID = sc.SpanID.String()
 
-		for k, v := range wrapper.Header() {
-			w.Header()[k] = v
+			SetNote(r, "trace_id", e.traceID)
+			SetNote(r, "span_id", e.spanID)
 		}
-		w.WriteHeader(wrapper.Code)
-		w.Write(wrapper.Body.Bytes())
 
-		now := time.Now()
-		timestamp := now.Format(o.dateFormat)
-		code := wrapper.Code
-		length := wrapper.Body.Len()
+		wrapper := handler.NewStreamWrapper(w)
 
-		o.logger.Print(fmt.Sprintf("%s - %s [%s - %s] \"%s %s\" %d %d \"%s\" %s",
-			remoteAddr, remoteUser, timestamp, now.Sub(start), method, uri, code, length, referer, userAgent))
-	})
-}
+		h.ServeHTTP(wrapper, r)
 
-func remoteAddr(r *http.Request) string {
-	hdr := r.Header
-	hdrRealIp := hdr.Get("X-Real-Ip")
-	hdrForwardedFor := hdr.Get("X-Forwarded-For")
-	if hdrRealIp == "" && hdrForwardedFor == "" {
-		return ipAddrFromRemoteAddr(r.RemoteAddr)
-	}
-	if hdrForwardedFor != "" {
-		// X-Forwarded-For is potentially a list of addresses separated with ","
-		parts := strings.Split(hdrForwardedFor, ",")
-		for i, p := range parts {
-			parts[i] = strings.TrimSpace(p)
-		}
-		return parts[0]
-	}
-	return hdrRealIp
-}
+		e.end = time.Now()
+		e.remoteUser = remoteUser(r, o.users)
+		e.status = wrapper.Status()
+		e.bytes = wrapper.Written
+		e.responseHeader = wrapper.Header()
 
-func ipAddrFromRemoteAddr(s string) string {
-	idx := strings.LastIndex(s, ":")
-	if idx == -1 {
-		return s
-	}
-	return s[:idx]
-}
-
-func remoteUser(r *http.Request) string {
-	if auth := r.Header.Get("Authorization"); auth != "" {
-		fields := strings.Fields(auth)
-		if len(fields) > 1 {
-			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+		if !filter.log(e) {
+			return
+		}
 
-			if err != nil {
-				return ""
+		if o.slogger != nil {
+			level := handler.LevelInfo
+			if e.status >= 500 {
+				level = handler.LevelError
+			} else if e.status >= 400 {
+				level = handler.LevelWarn
 			}
 
-			creds := strings.Split(string(decoded), ":")
-			if len(creds) > 1 {
-				return creds[0]
+			fields := append(e.fields,
+				handler.Field{Key: "remote_addr", Value: e.remoteAddr},
+				handler.Field{Key: "user", Value: e.remoteUser},
+				handler.Field{Key: "status", Value: e.status},
+				handler.Field{Key: "bytes", Value: e.bytes},
+				handler.Field{Key: "duration", Value: e.end.Sub(e.start)},
+				handler.Field{Key: "referer", Value: e.referer},
+				handler.Field{Key: "user_agent", Value: e.userAgent},
+			)
+			if e.requestID != "" {
+				fields = append(fields, handler.Field{Key: "request_id", Value: e.requestID})
+			}
+			if e.traceID != "" {
+				fields = append(fields,
+					handler.Field{Key: "trace_id", Value: e.traceID},
+					handler.Field{Key: "span_id", Value: e.spanID},
+				)
 			}
+
+			o.slogger.Log(level, "access", fields...)
+
+			return
 		}
-	}
 
-	return ""
+		o.logger.Print(o.format(e, o))
+	})
 }
 
 func (o *options) apply(opts []Option) {
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/urandom/handler"
 	"github.com/urandom/handler/log"
 )
 
@@ -19,13 +20,15 @@ func TestAccess(t *testing.T) {
 		method  string
 		code    int
 		resp    string
+		length  int
 		ip      string
 		ref     string
 		ua      string
 		message string
 	}{
-		{"/", "", "GET", 200, "test1", "1.2.3.4", "ref1", "ua1", `%s - %s [%s - {SPLITTER}] "%s %s" %d %d "%s" %s`},
-		{"/posted", "frank", "POST", 304, "test2.0", "10.0.0.5", "ref2", "ua2", `%s - %s [%s - {SPLITTER}] "%s %s" %d %d "%s" %s`},
+		{"/", "", "GET", 200, "test1", 5, "1.2.3.4", "ref1", "ua1", `%s - %s [%s - {SPLITTER}] "%s %s" %d %d "%s" %s`},
+		// A 304 response has no body, so nothing is written to the client
+		{"/posted", "frank", "POST", 304, "test2.0", 0, "10.0.0.5", "ref2", "ua2", `%s - %s [%s - {SPLITTER}] "%s %s" %d %d "%s" %s`},
 	}
 
 	for i, tc := range cases {
@@ -47,7 +50,7 @@ func TestAccess(t *testing.T) {
 			r.RemoteAddr = tc.ip
 			h.ServeHTTP(rec, r)
 
-			m := fmt.Sprintf(tc.message, tc.ip, tc.user, time.Now().Format(log.AccessDateFormat), tc.method, tc.uri, tc.code, len(tc.resp), tc.ref, tc.ua)
+			m := fmt.Sprintf(tc.message, tc.ip, tc.user, time.Now().Format(log.AccessDateFormat), tc.method, tc.uri, tc.code, tc.length, tc.ref, tc.ua)
 			parts := strings.Split(m, "{SPLITTER}")
 			first := l.message[0:len(parts[0])]
 
@@ -73,3 +76,81 @@ type logger struct {
 func (l *logger) Print(v ...interface{}) {
 	l.message = fmt.Sprint(v...)
 }
+
+func TestAccessStructured(t *testing.T) {
+	l := &structuredLogger{}
+	h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("test"))
+	}), log.StructuredLogger(l))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/foo?bar=baz", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if l.level != handler.LevelWarn {
+		t.Fatalf("expected level %s, got %s", handler.LevelWarn, l.level)
+	}
+
+	expected := map[string]interface{}{
+		"method":      "GET",
+		"path":        "/foo",
+		"remote_addr": "1.2.3.4",
+		"status":      http.StatusNotFound,
+		"bytes":       int64(4),
+	}
+
+	for k, v := range expected {
+		if l.fields[k] != v {
+			t.Fatalf("expected field %s to be %v, got %v", k, v, l.fields[k])
+		}
+	}
+
+	if _, ok := l.fields["duration"].(time.Duration); !ok {
+		t.Fatalf("expected a duration field, got %v", l.fields["duration"])
+	}
+}
+
+type structuredLogger struct {
+	level   handler.Level
+	message string
+	fields  map[string]interface{}
+}
+
+func (l *structuredLogger) Log(level handler.Level, msg string, fields ...handler.Field) {
+	l.level = level
+	l.message = msg
+	l.fields = map[string]interface{}{}
+	for _, f := range fields {
+		l.fields[f.Key] = f.Value
+	}
+}
+
+func TestAccessIPResolver(t *testing.T) {
+	trusted, _ := handler.NewTrustedProxies(handler.XForwardedForHeader, "10.0.0.0/8")
+
+	cases := []struct {
+		opts []log.Option
+		ip   string
+	}{
+		{nil, "10.0.0.1"},
+		{[]log.Option{log.IPResolver(trusted)}, "1.2.3.4"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+				append(tc.opts, log.Logger(l), log.Format("%h"))...)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.RemoteAddr = "10.0.0.1:5678"
+			r.Header.Set("X-Forwarded-For", "1.2.3.4")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if l.message != tc.ip {
+				t.Fatalf("expected ip %s, got %s", tc.ip, l.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+// SlowerThan causes the Access handler to log requests that took at least d
+// to serve. Unless a SampleRate is also given, all other requests, apart
+// from ones matching MinStatus or AlwaysLog, are not logged.
+func SlowerThan(d time.Duration) Option {
+	return Option{func(o *options) {
+		o.slowerThan = d
+	}}
+}
+
+// MinStatus causes the Access handler to log requests whose response status
+// code is at least code. Use 400 to log only error statuses. Unless a
+// SampleRate is also given, all other requests, apart from ones matching
+// SlowerThan or AlwaysLog, are not logged.
+func MinStatus(code int) Option {
+	return Option{func(o *options) {
+		o.minStatus = code
+	}}
+}
+
+// SampleRate causes the Access handler to log only a fraction of the
+// requests, given as a rate between 0 and 1. Sampling is deterministic: with
+// a rate of 0.1, every tenth request is logged. Requests matching
+// SlowerThan, MinStatus or AlwaysLog are always logged, and do not count
+// towards the sample.
+func SampleRate(rate float64) Option {
+	return Option{func(o *options) {
+		o.sampleRate = rate
+		o.sampled = true
+	}}
+}
+
+// AlwaysLog causes the Access handler to log all requests whose path matches
+// one of the patterns, regardless of any other filtering options. The
+// pattern syntax is the one used by path.Match, such as '/admin/*'.
+func AlwaysLog(patterns ...string) Option {
+	return Option{func(o *options) {
+		o.alwaysLog = append(o.alwaysLog, patterns...)
+	}}
+}
+
+// accessFilter decides whether an access entry is logged.
+type accessFilter struct {
+	slowerThan time.Duration
+	minStatus  int
+	alwaysLog  []string
+	rate       float64
+	count      uint64
+}
+
+func newAccessFilter(o options) *accessFilter {
+	f := &accessFilter{
+		slowerThan: o.slowerThan,
+		minStatus:  o.minStatus,
+		alwaysLog:  o.alwaysLog,
+		rate:       1,
+	}
+
+	if o.sampled {
+		f.rate = o.sampleRate
+	} else if o.slowerThan > 0 || o.minStatus > 0 {
+		f.rate = 0
+	}
+
+	return f
+}
+
+func (f *accessFilter) log(e accessEntry) bool {
+	if f.slowerThan > 0 && e.end.Sub(e.start) >= f.slowerThan {
+		return true
+	}
+
+	if f.minStatus > 0 && e.status >= f.minStatus {
+		return true
+	}
+
+	for _, p := range f.alwaysLog {
+		if ok, _ := path.Match(p, e.path); ok {
+			return true
+		}
+	}
+
+	return f.sample()
+}
+
+// sample logs the n-th request whenever the integer part of n*rate changes,
+// which spreads the logged requests evenly.
+func (f *accessFilter) sample() bool {
+	if f.rate >= 1 {
+		return true
+	}
+
+	if f.rate <= 0 {
+		return false
+	}
+
+	n := atomic.AddUint64(&f.count, 1)
+
+	return uint64(float64(n)*f.rate) != uint64(float64(n-1)*f.rate)
+}
@@ -0,0 +1,61 @@
+package log_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urandom/handler/log"
+)
+
+type countLogger struct {
+	count int
+}
+
+func (l *countLogger) Print(v ...interface{}) {
+	l.count++
+}
+
+func TestAccessFilter(t *testing.T) {
+	cases := []struct {
+		opts     []log.Option
+		path     string
+		code     int
+		delay    time.Duration
+		requests int
+		expected int
+	}{
+		{nil, "/", 200, 0, 10, 10},
+		{[]log.Option{log.MinStatus(400)}, "/", 200, 0, 10, 0},
+		{[]log.Option{log.MinStatus(400)}, "/", 404, 0, 10, 10},
+		{[]log.Option{log.SlowerThan(time.Hour)}, "/", 500, 0, 10, 0},
+		{[]log.Option{log.SlowerThan(time.Millisecond)}, "/", 200, 2 * time.Millisecond, 3, 3},
+		{[]log.Option{log.SampleRate(0.1)}, "/", 200, 0, 100, 10},
+		{[]log.Option{log.SampleRate(0.25), log.MinStatus(500)}, "/", 200, 0, 100, 25},
+		{[]log.Option{log.SampleRate(0.25), log.MinStatus(500)}, "/", 503, 0, 100, 100},
+		{[]log.Option{log.SampleRate(0)}, "/admin/users", 200, 0, 10, 0},
+		{[]log.Option{log.SampleRate(0), log.AlwaysLog("/login", "/admin/*")}, "/admin/users", 200, 0, 10, 10},
+		{[]log.Option{log.MinStatus(400), log.AlwaysLog("/admin/*")}, "/admin/users/1", 200, 0, 10, 0},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &countLogger{}
+			h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tc.delay)
+				w.WriteHeader(tc.code)
+			}), append(tc.opts, log.Logger(l))...)
+
+			for j := 0; j < tc.requests; j++ {
+				r, _ := http.NewRequest("GET", "http://localhost:8080"+tc.path, nil)
+				h.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			if l.count != tc.expected {
+				t.Fatalf("expected %d messages, got %d", tc.expected, l.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urandom/handler"
+)
+
+// CommonLogDateFormat is the timestamp format used by the Common and Combined
+// Log Formats.
+const CommonLogDateFormat = "02/Jan/2006:15:04:05 -0700"
+
+const (
+	// CommonFormat is the Format string of the Common Log Format.
+	CommonFormat = `%h %l %u %t "%r" %>s %b`
+
+	// CombinedFormat is the Format string of the Combined Log Format.
+	CombinedFormat = CommonFormat + ` "%{Referer}i" "%{User-Agent}i"`
+)
+
+var (
+	// CommonLogFormat causes the Access handler to write its messages in
+	// the Common Log Format:
+	//
+	// IP - USER [DATETIME] "HTTP_METHOD URI PROTOCOL" STATUS_CODE BODY_LENGTH
+	//
+	// The timestamp is the time the request was received, formatted using
+	// CommonLogDateFormat. Missing values are replaced with '-'.
+	CommonLogFormat = Format(CommonFormat)
+
+	// CombinedLogFormat causes the Access handler to write its messages in
+	// the Combined Log Format, which extends the Common Log Format with the
+	// referer and user agent:
+	//
+	// IP - USER [DATETIME] "HTTP_METHOD URI PROTOCOL" STATUS_CODE BODY_LENGTH "REFERER" "USER_AGENT"
+	CombinedLogFormat = Format(CombinedFormat)
+)
+
+// Format causes the Access handler to write its messages according to the
+// format string f. The string is compiled once, when the handler is created.
+// Similar to Apache's mod_log_config, it may contain the following
+// directives:
+//
+//	%%          a literal percent sign
+//	%a, %h      the client IP address
+//	%l          the remote logname, always '-'
+//	%u          the remote user
+//	%t          the time the request was received, in CommonLogDateFormat
+//	%{LAYOUT}t  the time the request was received, in the given time layout
+//	%r          the request line
+//	%m          the request method
+//	%U          the request path
+//	%q          the query string, prefixed with '?', or an empty string
+//	%H          the request protocol
+//	%s, %>s     the response status code
+//	%b          the response body size in bytes, or '-' when empty
+//	%B          the response body size in bytes
+//	%D          the time taken to serve the request, in microseconds
+//	%T          the time taken to serve the request, in seconds
+//	%L          the request ID, assigned by the trace.RequestID handler
+//	%{NAME}i    the value of the NAME request header
+//	%{NAME}o    the value of the NAME response header
+//	%{NAME}n    the value of the NAME note, set via SetNote, or stored in
+//	            the request context under NoteKey(NAME). The trace_id and
+//	            span_id notes hold the IDs of the trace.Trace span
+//
+// Missing values are replaced with '-', and quotes, backslashes and control
+// characters in user-provided values are escaped. Unknown directives are
+// written out verbatim.
+func Format(f string) Option {
+	format := compileFormat(f)
+
+	return Option{func(o *options) {
+		o.format = format
+	}}
+}
+
+// accessEntry holds the information about a single request, as recorded by
+// the Access handler.
+type accessEntry struct {
+	start          time.Time
+	end            time.Time
+	remoteAddr     string
+	remoteUser     string
+	requestID      string
+	traceID        string
+	spanID         string
+	method         string
+	uri            string
+	path           string
+	query          string
+	proto          string
+	status         int
+	bytes          int64
+	referer        string
+	userAgent      string
+	fields         []handler.Field
+	request        *http.Request
+	responseHeader http.Header
+}
+
+type accessFormatter func(e accessEntry, o options) string
+
+// formatDirective writes a single compiled part of a format string.
+type formatDirective func(b *strings.Builder, e accessEntry)
+
+func defaultFormat(e accessEntry, o options) string {
+	s := fmt.Sprintf("%s - %s [%s - %s] \"%s %s\" %d %d \"%s\" %s",
+		e.remoteAddr, e.remoteUser, e.end.Format(o.dateFormat), e.end.Sub(e.start),
+		e.method, e.uri, e.status, e.bytes, e.referer, e.userAgent)
+
+	if e.requestID != "" {
+		s += " " + e.requestID
+	}
+
+	return s
+}
+
+func compileFormat(f string) accessFormatter {
+	var directives []formatDirective
+
+	literal := func(s string) {
+		directives = append(directives, func(b *strings.Builder, e accessEntry) {
+			b.WriteString(s)
+		})
+	}
+
+	value := func(v func(e accessEntry) string) {
+		directives = append(directives, func(b *strings.Builder, e accessEntry) {
+			b.WriteString(orDash(escape(v(e))))
+		})
+	}
+
+	for len(f) > 0 {
+		i := strings.IndexByte(f, '%')
+		if i == -1 {
+			literal(f)
+			break
+		}
+
+		if i > 0 {
+			literal(f[:i])
+		}
+
+		// Directives are at least two characters long
+		if i+1 >= len(f) {
+			literal(f[i:])
+			break
+		}
+
+		f = f[i+1:]
+
+		var arg string
+		if f[0] == '{' {
+			end := strings.IndexByte(f, '}')
+			if end == -1 || end+1 >= len(f) {
+				literal("%" + f)
+				break
+			}
+
+			arg = f[1:end]
+			f = f[end+1:]
+		} else if f[0] == '>' && len(f) > 1 {
+			// Final status, the only one this handler knows about
+			f = f[1:]
+		}
+
+		c := f[0]
+		f = f[1:]
+
+		switch c {
+		case '%':
+			literal("%")
+		case 'a', 'h':
+			value(func(e accessEntry) string { return e.remoteAddr })
+		case 'l':
+			literal("-")
+		case 'u':
+			value(func(e accessEntry) string { return e.remoteUser })
+		case 't':
+			layout := "[" + CommonLogDateFormat + "]"
+			if arg != "" {
+				layout = arg
+			}
+			directives = append(directives, func(b *strings.Builder, e accessEntry) {
+				b.WriteString(e.start.Format(layout))
+			})
+		case 'r':
+			value(func(e accessEntry) string { return e.method + " " + e.uri + " " + e.proto })
+		case 'm':
+			value(func(e accessEntry) string { return e.method })
+		case 'U':
+			value(func(e accessEntry) string { return e.path })
+		case 'q':
+			directives = append(directives, func(b *strings.Builder, e accessEntry) {
+				if e.query != "" {
+					b.WriteString("?" + escape(e.query))
+				}
+			})
+		case 'H':
+			value(func(e accessEntry) string { return e.proto })
+		case 's':
+			value(func(e accessEntry) string { return strconv.Itoa(e.status) })
+		case 'b':
+			value(func(e accessEntry) string { return bytesOrDash(e.bytes) })
+		case 'B':
+			value(func(e accessEntry) string { return strconv.FormatInt(e.bytes, 10) })
+		case 'D':
+			value(func(e accessEntry) string {
+				return strconv.FormatInt(int64(e.end.Sub(e.start)/time.Microsecond), 10)
+			})
+		case 'T':
+			value(func(e accessEntry) string {
+				return strconv.FormatInt(int64(e.end.Sub(e.start)/time.Second), 10)
+			})
+		case 'L':
+			value(func(e accessEntry) string { return e.requestID })
+		case 'i':
+			value(func(e accessEntry) string { return e.request.Header.Get(arg) })
+		case 'o':
+			value(func(e accessEntry) string { return e.responseHeader.Get(arg) })
+		case 'n':
+			value(func(e accessEntry) string { return note(e.request, arg) })
+		default:
+			if arg != "" {
+				literal("%{" + arg + "}" + string(c))
+			} else {
+				literal("%" + string(c))
+			}
+		}
+	}
+
+	return func(e accessEntry, o options) string {
+		var b strings.Builder
+		for _, d := range directives {
+			d(&b, e)
+		}
+
+		return b.String()
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+func bytesOrDash(b int64) string {
+	if b == 0 {
+		return "-"
+	}
+
+	return strconv.FormatInt(b, 10)
+}
+
+// escape escapes quotes, backslashes and non-printable characters, the same
+// way Apache does, so that a value can't break out of a quoted field.
+func escape(s string) string {
+	if !needsEscape(s) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '"' || c == '\\' || c < 0x20 || c >= 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
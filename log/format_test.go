@@ -0,0 +1,99 @@
+package log_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/urandom/handler/log"
+	"github.com/urandom/handler/trace"
+)
+
+func TestAccessFormats(t *testing.T) {
+	cases := []struct {
+		format  log.Option
+		user    string
+		resp    string
+		ua      string
+		pattern string
+	}{
+		{
+			log.CommonLogFormat, "", "test1", "ua1",
+			`^1\.2\.3\.4 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /foo\?bar=baz HTTP/1\.1" 200 5$`,
+		},
+		{
+			log.CommonLogFormat, "frank", "", "ua1",
+			`^1\.2\.3\.4 - frank \[[^]]+\] "GET /foo\?bar=baz HTTP/1\.1" 200 -$`,
+		},
+		{
+			log.CombinedLogFormat, "frank", "test1", `ua "quoted"`,
+			`^1\.2\.3\.4 - frank \[[^]]+\] "GET /foo\?bar=baz HTTP/1\.1" 200 5 "-" "ua \\"quoted\\""$`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tc.resp))
+			}), log.Logger(l), tc.format)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080/foo?bar=baz", nil)
+			r.RemoteAddr = "1.2.3.4:5678"
+			r.Header.Set("User-Agent", tc.ua)
+			if tc.user != "" {
+				r.SetBasicAuth(tc.user, "foobar")
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if !regexp.MustCompile(tc.pattern).MatchString(l.message) {
+				t.Fatalf("message %s doesn't match %s", l.message, tc.pattern)
+			}
+		})
+	}
+}
+
+func TestAccessCustomFormat(t *testing.T) {
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{`%m %U%q %H %>s %B`, `POST /foo?bar=baz HTTP/1.1 201 5`},
+		{`%{X-Request-Id}i %{Content-Type}o %{upstream}n %{missing}i`, `req-1 text/plain 12ms -`},
+		{`%{ctx}n %{02 Jan 2006}t`, `from-context ` + time.Now().Format("02 Jan 2006")},
+		{`100%% %l %x %{foo}x %`, `100% - %x %{foo}x %`},
+		{`%D`, `^\d+$`},
+		{`%L %{X-Request-Id}o`, `req-1 req-1`},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			h := trace.RequestID(log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.SetNote(r, "upstream", "12ms")
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("test1"))
+			}), log.Logger(l), log.Format(tc.format)))
+
+			r, _ := http.NewRequest("POST", "http://localhost:8080/foo?bar=baz", nil)
+			r.Header.Set("X-Request-Id", "req-1")
+			r = r.WithContext(context.WithValue(r.Context(), log.NoteKey("ctx"), "from-context"))
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tc.expected[0] == '^' {
+				if !regexp.MustCompile(tc.expected).MatchString(l.message) {
+					t.Fatalf("message %s doesn't match %s", l.message, tc.expected)
+				}
+			} else if l.message != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, l.message)
+			}
+		})
+	}
+}
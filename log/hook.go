@@ -0,0 +1,189 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urandom/handler/trace"
+)
+
+// RedactedValue replaces the values of sensitive headers in the request
+// passed to panic hooks.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders are the request headers whose values are redacted
+// before the request is passed to panic hooks.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Csrf-Token"}
+
+// StackFrame is a single function call in the stack of a panicking
+// goroutine.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s\n\t%s:%d", f.Function, f.File, f.Line)
+}
+
+// PanicReport describes a recovered panic to the panic hooks.
+type PanicReport struct {
+	// Value is the recovered value.
+	Value interface{}
+	// Frames is the stack of the panicking goroutine, starting with the
+	// function that panicked.
+	Frames []StackFrame
+	// Request is a copy of the request, with the sensitive headers
+	// redacted.
+	Request *http.Request
+	// RequestID identifies the request. It is the one assigned by the
+	// trace.RequestID handler, if present, or generated otherwise.
+	RequestID string
+	// Time is the time the panic was recovered.
+	Time time.Time
+}
+
+// A PanicHook is notified whenever the Panic handler recovers from a panic.
+// It may be used to send reports to an error tracker, or to count the
+// number of crashes. Hooks are invoked synchronously, after the response is
+// written, and any panics in them are ignored.
+type PanicHook interface {
+	OnPanic(report PanicReport)
+}
+
+// The PanicHookFunc type is an adapter to allow using ordinary functions as
+// panic hooks.
+type PanicHookFunc func(report PanicReport)
+
+func (f PanicHookFunc) OnPanic(report PanicReport) {
+	f(report)
+}
+
+// Hooks adds panic hooks to the Panic handler.
+func Hooks(hooks ...PanicHook) Option {
+	return Option{func(o *options) {
+		o.hooks = append(o.hooks, hooks...)
+	}}
+}
+
+// RedactHeaders sets the request headers whose values are redacted before
+// the request is passed to panic hooks, replacing DefaultRedactedHeaders.
+func RedactHeaders(names ...string) Option {
+	return Option{func(o *options) {
+		o.redactHeaders = names
+	}}
+}
+
+// CrashDump returns a panic hook that writes a crash report into a new file
+// in dir, named after the time and the request ID. The report contains the
+// recovered value, the redacted request and the stack. Errors are reported to
+// os.Stderr.
+func CrashDump(dir string) PanicHook {
+	return PanicHookFunc(func(report PanicReport) {
+		name := fmt.Sprintf("crash-%s-%s.txt", report.Time.Format("20060102T150405.000"), sanitizeID(report.RequestID))
+
+		if err := writeCrashDump(filepath.Join(dir, name), report); err != nil {
+			fmt.Fprintln(os.Stderr, "crash dump:", err)
+		}
+	})
+}
+
+func writeCrashDump(path string, report PanicReport) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "panic: %v\n", report.Value)
+	fmt.Fprintf(&b, "time: %s\n", report.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "request id: %s\n\n", report.RequestID)
+
+	if dump, err := httputil.DumpRequest(report.Request, false); err == nil {
+		b.Write(dump)
+	}
+
+	for _, f := range report.Frames {
+		b.WriteString(f.String())
+		b.WriteByte('\n')
+	}
+
+	return ioutil.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// sanitizeID keeps a request ID from escaping the dump directory.
+func sanitizeID(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+
+		return -1
+	}, id)
+}
+
+// panicFrames returns the stack frames of the panicking goroutine. It has to
+// be called directly from the deferred function that recovers.
+func panicFrames() []StackFrame {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(1, pc)
+
+	var frames []StackFrame
+	it := runtime.CallersFrames(pc[:n])
+	for {
+		f, more := it.Next()
+		frames = append(frames, StackFrame{Function: f.Function, File: f.File, Line: f.Line})
+
+		if f.Function == "runtime.gopanic" {
+			// Everything up to here belongs to the recovery
+			frames = frames[:0]
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// redactRequest returns a copy of r, with the values of the named headers
+// replaced.
+func redactRequest(r *http.Request, names []string) *http.Request {
+	c := r.Clone(r.Context())
+
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		if values, ok := c.Header[name]; ok {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = RedactedValue
+			}
+			c.Header[name] = redacted
+		}
+	}
+
+	return c
+}
+
+func requestID(r *http.Request) string {
+	if id := trace.RequestIDFrom(r.Context()); id != "" {
+		return id
+	}
+
+	return trace.UUIDv7()
+}
+
+func runHooks(hooks []PanicHook, report PanicReport) {
+	for _, h := range hooks {
+		func() {
+			defer func() { recover() }()
+
+			h.OnPanic(report)
+		}()
+	}
+}
@@ -0,0 +1,135 @@
+package log_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler"
+	"github.com/urandom/handler/log"
+	"github.com/urandom/handler/trace"
+)
+
+func TestPanicHooks(t *testing.T) {
+	cases := []struct {
+		id      string
+		redact  []log.Option
+		headers map[string]string
+		visible []string
+		hidden  []string
+	}{
+		{"abc-123", nil, map[string]string{"Authorization": "Bearer secret", "Cookie": "session=secret", "Accept": "text/html"}, []string{"Accept"}, []string{"Authorization", "Cookie"}},
+		{"", []log.Option{log.RedactHeaders("X-Token")}, map[string]string{"Authorization": "Bearer visible", "X-Token": "secret"}, []string{"Authorization"}, []string{"X-Token"}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var reports []log.PanicReport
+			count := 0
+
+			opts := append([]log.Option{
+				log.Logger(handler.NopLogger()),
+				log.Hooks(log.PanicHookFunc(func(report log.PanicReport) {
+					reports = append(reports, report)
+				})),
+				log.Hooks(log.PanicHookFunc(func(report log.PanicReport) {
+					panic("broken hook")
+				}), log.PanicHookFunc(func(report log.PanicReport) {
+					count++
+				})),
+			}, tc.redact...)
+
+			h := log.Panic(http.HandlerFunc(panickingHandler), opts...)
+			if tc.id != "" {
+				h = trace.RequestID(h)
+			}
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			if tc.id != "" {
+				r.Header.Set("X-Request-Id", tc.id)
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(reports) != 1 || count != 1 {
+				t.Fatalf("expected all hooks to be called once, got %d and %d", len(reports), count)
+			}
+
+			report := reports[0]
+			if report.Value != "Test" {
+				t.Fatalf("expected value %v, got %v", "Test", report.Value)
+			}
+
+			if tc.id != "" && report.RequestID != tc.id {
+				t.Fatalf("expected request id %s, got %s", tc.id, report.RequestID)
+			} else if report.RequestID == "" {
+				t.Fatalf("expected a generated request id")
+			}
+
+			if len(report.Frames) == 0 || !strings.HasSuffix(report.Frames[0].Function, "log_test.panickingHandler") {
+				t.Fatalf("expected the first frame to be the panicking function, got %v", report.Frames)
+			}
+
+			for _, name := range tc.visible {
+				if report.Request.Header.Get(name) != tc.headers[name] {
+					t.Fatalf("expected header %s to be %s, got %s", name, tc.headers[name], report.Request.Header.Get(name))
+				}
+			}
+
+			for _, name := range tc.hidden {
+				if report.Request.Header.Get(name) != log.RedactedValue {
+					t.Fatalf("expected header %s to be redacted, got %s", name, report.Request.Header.Get(name))
+				}
+
+				if r.Header.Get(name) != tc.headers[name] {
+					t.Fatalf("expected the original request to be unchanged")
+				}
+			}
+		})
+	}
+}
+
+func TestCrashDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crash")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := trace.RequestID(log.Panic(http.HandlerFunc(panickingHandler), log.Logger(handler.NopLogger()), log.Hooks(log.CrashDump(dir))))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/crash", nil)
+	r.Header.Set("X-Request-Id", "../../id")
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "crash-*-id.txt"))
+	if len(files) != 1 {
+		t.Fatalf("expected a single crash dump, got %v", files)
+	}
+
+	b, _ := ioutil.ReadFile(files[0])
+	dump := string(b)
+
+	for _, s := range []string{"panic: Test", "GET /crash HTTP/1.1", "Authorization: " + log.RedactedValue, "log_test.panickingHandler"} {
+		if !strings.Contains(dump, s) {
+			t.Fatalf("expected dump to contain %q, got %s", s, dump)
+		}
+	}
+
+	if strings.Contains(dump, "secret") {
+		t.Fatalf("expected the authorization header to be redacted, got %s", dump)
+	}
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("Test")
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JSONFormat causes the Access handler to write each message as a single
+// line JSON object, with the following keys:
+//
+//	time, remote_addr, user, method, uri, protocol, status, bytes,
+//	duration_us, referer, user_agent, request_id, trace_id, span_id,
+//	request_headers, response_headers
+//
+// The request ID is omitted when the handler isn't wrapped by the
+// trace.RequestID handler, and the trace and span IDs when it isn't wrapped
+// by the trace.Trace handler. The header objects contain the headers
+// selected by the RequestHeaders and ResponseHeaders options, and are
+// omitted when empty.
+var JSONFormat = Option{func(o *options) {
+	o.format = jsonFormat
+}}
+
+// RequestHeaders selects the request headers included in JSONFormat
+// messages.
+func RequestHeaders(names ...string) Option {
+	return Option{func(o *options) {
+		o.requestHeaders = names
+	}}
+}
+
+// ResponseHeaders selects the response headers included in JSONFormat
+// messages.
+func ResponseHeaders(names ...string) Option {
+	return Option{func(o *options) {
+		o.responseHeaders = names
+	}}
+}
+
+type jsonEntry struct {
+	Time            string            `json:"time"`
+	RemoteAddr      string            `json:"remote_addr"`
+	User            string            `json:"user"`
+	Method          string            `json:"method"`
+	URI             string            `json:"uri"`
+	Protocol        string            `json:"protocol"`
+	Status          int               `json:"status"`
+	Bytes           int64             `json:"bytes"`
+	Duration        int64             `json:"duration_us"`
+	Referer         string            `json:"referer"`
+	UserAgent       string            `json:"user_agent"`
+	RequestID       string            `json:"request_id,omitempty"`
+	TraceID         string            `json:"trace_id,omitempty"`
+	SpanID          string            `json:"span_id,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+}
+
+func jsonFormat(e accessEntry, o options) string {
+	entry := jsonEntry{
+		Time:            e.start.Format(time.RFC3339Nano),
+		RemoteAddr:      e.remoteAddr,
+		User:            e.remoteUser,
+		Method:          e.method,
+		URI:             e.uri,
+		Protocol:        e.proto,
+		Status:          e.status,
+		Bytes:           e.bytes,
+		Duration:        int64(e.end.Sub(e.start) / time.Microsecond),
+		Referer:         e.referer,
+		UserAgent:       e.userAgent,
+		RequestID:       e.requestID,
+		TraceID:         e.traceID,
+		SpanID:          e.spanID,
+		RequestHeaders:  selectHeaders(e.request.Header, o.requestHeaders),
+		ResponseHeaders: selectHeaders(e.responseHeader, o.responseHeaders),
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	// Encoding strings and numbers can't fail
+	enc.Encode(entry)
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func selectHeaders(h http.Header, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	selected := map[string]string{}
+	for _, name := range names {
+		if values := h[http.CanonicalHeaderKey(name)]; len(values) > 0 {
+			selected[name] = strings.Join(values, ", ")
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	return selected
+}
@@ -0,0 +1,100 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler/log"
+	"github.com/urandom/handler/trace"
+)
+
+func TestAccessJSON(t *testing.T) {
+	l := &logger{}
+	h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("test"))
+	}), log.Logger(l), log.JSONFormat, log.RequestHeaders("X-Request-Id", "X-Missing"), log.ResponseHeaders("content-type"))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/foo?bar=baz", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.SetBasicAuth("frank", "foobar")
+	r.Header.Set("User-Agent", `ua "quoted" <tag>`)
+	r.Header.Set("X-Request-Id", "req-1")
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if strings.Contains(l.message, "\n") {
+		t.Fatalf("expected a single line, got %s", l.message)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(l.message), &entry); err != nil {
+		t.Fatalf("invalid json %s: %s", l.message, err)
+	}
+
+	expected := map[string]interface{}{
+		"remote_addr": "1.2.3.4",
+		"user":        "frank",
+		"method":      "GET",
+		"uri":         "/foo?bar=baz",
+		"protocol":    "HTTP/1.1",
+		"status":      float64(http.StatusTeapot),
+		"bytes":       float64(4),
+		"referer":     "",
+		"user_agent":  `ua "quoted" <tag>`,
+	}
+
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Fatalf("expected %s to be %v, got %v", k, v, entry[k])
+		}
+	}
+
+	if _, ok := entry["duration_us"].(float64); !ok {
+		t.Fatalf("expected a numeric duration, got %v", entry["duration_us"])
+	}
+
+	reqHeaders, _ := entry["request_headers"].(map[string]interface{})
+	if len(reqHeaders) != 1 || reqHeaders["X-Request-Id"] != "req-1" {
+		t.Fatalf("unexpected request headers %v", entry["request_headers"])
+	}
+
+	respHeaders, _ := entry["response_headers"].(map[string]interface{})
+	if len(respHeaders) != 1 || respHeaders["content-type"] != "text/plain" {
+		t.Fatalf("unexpected response headers %v", entry["response_headers"])
+	}
+}
+
+func TestAccessJSONTrace(t *testing.T) {
+	l := &logger{}
+	h := trace.Trace(trace.RequestID(log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test"))
+	}), log.Logger(l), log.JSONFormat)))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/foo", nil)
+	r.Header.Set("X-Request-Id", "req-1")
+	r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(l.message), &entry); err != nil {
+		t.Fatalf("invalid json %s: %s", l.message, err)
+	}
+
+	if entry["request_id"] != "req-1" {
+		t.Fatalf("expected request id req-1, got %v", entry["request_id"])
+	}
+
+	if entry["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the incoming trace id, got %v", entry["trace_id"])
+	}
+
+	if id, _ := entry["span_id"].(string); len(id) != 16 || id == "00f067aa0ba902b7" {
+		t.Fatalf("expected a new span id, got %v", entry["span_id"])
+	}
+}
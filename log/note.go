@@ -0,0 +1,31 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/urandom/handler"
+)
+
+// NoteKey is a request context key, whose value may be referenced in an
+// access log Format string using the %{NAME}n directive.
+type NoteKey string
+
+// SetNote records a value for the access log message of the current request,
+// which may be referenced in a Format string using the %{NAME}n directive.
+// It has no effect if the request isn't served by an Access handler.
+func SetNote(r *http.Request, name, value string) {
+	handler.SetNote(r, name, value)
+}
+
+func note(r *http.Request, name string) string {
+	if v, ok := handler.Note(r, name); ok {
+		return v
+	}
+
+	if v := r.Context().Value(NoteKey(name)); v != nil {
+		return fmt.Sprint(v)
+	}
+
+	return ""
+}
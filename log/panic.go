@@ -24,30 +24,98 @@ var (
 // Panic returns a handler that invokes the passed handler h, catching any
 // panics. If one occurs, an HTTP 500 response is produced.
 //
+// The response is written by the ErrorRenderer given via the Renderer option,
+// TextRenderer by default. NegotiatedRenderer produces HTML, JSON or
+// problem+json responses, depending on what the client accepts.
+//
+// The logged message contains the recovered value, the request ID and the
+// stack. The ID is the one assigned by the trace.RequestID handler, if
+// present, or a generated one, which is also included in the rendered error.
+// If a StructuredLogger is provided, the same data is passed to it as fields
+// of an error message.
+//
+// Panic hooks, added via the Hooks option, receive a PanicReport, with the
+// sensitive request headers redacted.
+//
+// If the response has already been committed when the panic occurs, an
+// error response can no longer be sent. The panic is still logged and
+// reported, after which the connection is aborted by panicking with
+// http.ErrAbortHandler, so that the client doesn't mistake the partial
+// response for a complete one. A panic with http.ErrAbortHandler itself is
+// not treated as a crash, and is propagated without being logged.
+//
 // By default, all messages are printed out to os.Stderr.
 func Panic(h http.Handler, opts ...Option) http.Handler {
-	o := options{logger: handler.ErrLogger(), dateFormat: PanicDateFormat}
+	o := options{
+		logger:        handler.ErrLogger(),
+		dateFormat:    PanicDateFormat,
+		renderer:      TextRenderer,
+		redactHeaders: DefaultRedactedHeaders,
+	}
 	o.apply(opts)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapper := handler.NewStreamWrapper(w)
+
 		defer func() {
 			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				frames := panicFrames()
 				stack := debug.Stack()
-				timestamp := time.Now().Format(o.dateFormat)
-				message := fmt.Sprintf("%s - %s\n%s\n", timestamp, rec, stack)
+				now := time.Now()
+				id := requestID(r)
+				message := fmt.Sprintf("%s - %s - request %s\n%s\n", now.Format(o.dateFormat), rec, id, stack)
 
-				o.logger.Print(message)
+				if o.slogger != nil {
+					o.slogger.Log(handler.LevelError, "panic", append(handler.RequestFields(r),
+						handler.Field{Key: "error", Value: rec},
+						handler.Field{Key: "stack", Value: string(stack)},
+						handler.Field{Key: "request_id", Value: id},
+					)...)
+				} else {
+					o.logger.Print(message)
+				}
+
+				committed := wrapper.Committed()
+				if !committed {
+					info := PanicInfo{
+						Status:    http.StatusInternalServerError,
+						Title:     http.StatusText(http.StatusInternalServerError),
+						Value:     rec,
+						RequestID: id,
+						Time:      now,
+					}
+
+					if o.showStack {
+						info.Detail = message
+					}
 
-				w.WriteHeader(http.StatusInternalServerError)
+					// Headers describing the unfinished body no longer apply
+					wrapper.Header().Del("Content-Length")
+					wrapper.Header().Del("Content-Encoding")
 
-				if !o.showStack {
-					message = "Internal Server Error"
+					o.renderer.Render(wrapper, r, info)
 				}
 
-				w.Write([]byte(message))
+				if len(o.hooks) > 0 {
+					runHooks(o.hooks, PanicReport{
+						Value:     rec,
+						Frames:    frames,
+						Request:   redactRequest(r, o.redactHeaders),
+						RequestID: id,
+						Time:      now,
+					})
+				}
+
+				if committed {
+					panic(http.ErrAbortHandler)
+				}
 			}
 		}()
 
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(wrapper, r)
 	})
 }
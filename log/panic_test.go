@@ -10,6 +10,7 @@ import (
 
 	"github.com/urandom/handler"
 	"github.com/urandom/handler/log"
+	"github.com/urandom/handler/trace"
 )
 
 func TestPanic(t *testing.T) {
@@ -63,3 +64,168 @@ func TestPanic(t *testing.T) {
 	}
 
 }
+
+func TestPanicRenderer(t *testing.T) {
+	cases := []struct {
+		accept      string
+		showStack   bool
+		contentType string
+		body        string
+	}{
+		{"", false, "text/plain; charset=utf-8", "Internal Server Error"},
+		{"*/*", false, "text/plain; charset=utf-8", "Internal Server Error"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false, "text/html; charset=utf-8", "<h1>500 Internal Server Error</h1>"},
+		{"application/json", false, "application/json", `{"status":500,"error":"Internal Server Error"}`},
+		{"application/problem+json, application/json;q=0.9", false, "application/problem+json", `{"type":"about:blank","title":"Internal Server Error","status":500}`},
+		{"application/json;q=0.5, */*", false, "application/json", `{"status":500,"error":"Internal Server Error"}`},
+		{"text/*;q=0.5, text/plain;q=0", false, "text/html; charset=utf-8", "<title>500 Internal Server Error</title>"},
+		{"image/png", false, "text/plain; charset=utf-8", "Internal Server Error"},
+		{"application/json", true, "application/json", `"detail":"` + time.Now().Format(log.PanicDateFormat) + ` - Test - request `},
+		{"text/html", true, "text/html; charset=utf-8", "<pre>" + time.Now().Format(log.PanicDateFormat) + " - Test - request "},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			opts := []log.Option{log.Logger(handler.NopLogger()), log.Renderer(log.NegotiatedRenderer)}
+			if tc.showStack {
+				opts = append(opts, log.ShowStack)
+			}
+
+			h := log.Panic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("Test")
+			}), opts...)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("got code %d, wanted %d", rec.Code, http.StatusInternalServerError)
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != tc.contentType {
+				t.Fatalf("got content type %s, expected %s", ct, tc.contentType)
+			}
+
+			if !strings.Contains(rec.Body.String(), tc.body) {
+				t.Fatalf("got body %s, expected it to contain %s", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestPanicCommitted(t *testing.T) {
+	cases := []struct {
+		panic     interface{}
+		write     bool
+		logged    bool
+		aborted   bool
+		code      int
+		body      string
+		hookCalls int
+	}{
+		{"Test", false, true, false, http.StatusInternalServerError, "Internal Server Error", 1},
+		{"Test", true, true, true, http.StatusOK, "partial", 1},
+		{http.ErrAbortHandler, false, false, true, http.StatusOK, "", 0},
+		{http.ErrAbortHandler, true, false, true, http.StatusOK, "partial", 0},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			hookCalls := 0
+
+			h := log.Panic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.write {
+					w.Header().Set("Content-Length", "100")
+					w.Write([]byte("partial"))
+				}
+				panic(tc.panic)
+			}), log.Logger(l), log.Hooks(log.PanicHookFunc(func(report log.PanicReport) {
+				hookCalls++
+			})))
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			rec := httptest.NewRecorder()
+
+			aborted := func() (aborted bool) {
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							t.Fatalf("expected http.ErrAbortHandler, got %v", p)
+						}
+						aborted = true
+					}
+				}()
+
+				h.ServeHTTP(rec, r)
+				return false
+			}()
+
+			if aborted != tc.aborted {
+				t.Fatalf("expected aborted to be %v", tc.aborted)
+			}
+
+			if (l.message != "") != tc.logged {
+				t.Fatalf("expected logged to be %v, got %q", tc.logged, l.message)
+			}
+
+			if hookCalls != tc.hookCalls {
+				t.Fatalf("expected %d hook calls, got %d", tc.hookCalls, hookCalls)
+			}
+
+			if rec.Code != tc.code {
+				t.Fatalf("got code %d, wanted %d", rec.Code, tc.code)
+			}
+
+			if rec.Body.String() != tc.body {
+				t.Fatalf("got body %s, expected %s", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestPanicRequestID(t *testing.T) {
+	cases := []struct {
+		wrap bool
+		id   string
+	}{
+		{true, "req-1"},
+		{false, ""},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			var reported string
+
+			var h http.Handler = log.Panic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("Test")
+			}), log.Logger(l), log.Hooks(log.PanicHookFunc(func(report log.PanicReport) {
+				reported = report.RequestID
+			})))
+
+			if tc.wrap {
+				h = trace.RequestID(h)
+			}
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.Header.Set(trace.RequestIDHeader, "req-1")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tc.id != "" && reported != tc.id {
+				t.Fatalf("expected request id %s, got %s", tc.id, reported)
+			}
+
+			// Without the trace.RequestID handler, the id is generated
+			expected := fmt.Sprintf("Test - request %s\n", reported)
+			if reported == "" || !strings.Contains(l.message, expected) {
+				t.Fatalf("expected message containing %q, got %q", expected, l.message)
+			}
+		})
+	}
+}
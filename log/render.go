@@ -0,0 +1,230 @@
+package log
+
+import (
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PanicInfo describes a recovered panic to an ErrorRenderer.
+type PanicInfo struct {
+	// Status is the HTTP status code of the response.
+	Status int
+	// Title is the status text of the response.
+	Title string
+	// Detail contains the recovered value and the stack trace, and is only
+	// set when the ShowStack option is used.
+	Detail string
+	// Value is the recovered value. It should not be exposed to clients.
+	Value interface{}
+	// RequestID identifies the request, and may be shown to clients so
+	// that they can refer to it.
+	RequestID string
+	// Time is the time the panic was recovered.
+	Time time.Time
+}
+
+// ErrorRenderer writes the response of the Panic handler.
+type ErrorRenderer interface {
+	Render(w http.ResponseWriter, r *http.Request, info PanicInfo)
+}
+
+// The ErrorRendererFunc type is an adapter to allow using ordinary functions
+// as error renderers.
+type ErrorRendererFunc func(w http.ResponseWriter, r *http.Request, info PanicInfo)
+
+func (f ErrorRendererFunc) Render(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+	f(w, r, info)
+}
+
+// DefaultErrorTemplate is the template used by HTMLRenderer when none is
+// provided. It is executed with a PanicInfo.
+var DefaultErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{- if .Detail}}
+<pre>{{.Detail}}</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+var (
+	// TextRenderer writes the status text, or the detail, if present, as
+	// plain text. It is the default renderer of the Panic handler.
+	TextRenderer ErrorRenderer = ErrorRendererFunc(renderText)
+
+	// JSONRenderer writes a JSON object with 'status', 'error' and
+	// optionally 'detail' keys.
+	JSONRenderer ErrorRenderer = ErrorRendererFunc(renderJSON)
+
+	// ProblemRenderer writes an RFC 7807 application/problem+json document.
+	ProblemRenderer ErrorRenderer = ErrorRendererFunc(renderProblem)
+
+	// NegotiatedRenderer picks the HTML, JSON, problem+json or plain text
+	// renderer, based on the request Accept header, defaulting to plain
+	// text.
+	NegotiatedRenderer = Negotiate(map[string]ErrorRenderer{
+		"text/html":                HTMLRenderer(nil),
+		"application/json":         JSONRenderer,
+		"application/problem+json": ProblemRenderer,
+		"text/plain":               TextRenderer,
+	}, TextRenderer)
+)
+
+// Renderer defines how the Panic handler writes its response.
+func Renderer(r ErrorRenderer) Option {
+	return Option{func(o *options) {
+		o.renderer = r
+	}}
+}
+
+// HTMLRenderer returns a renderer that executes the template t with the
+// PanicInfo. If t is nil, DefaultErrorTemplate is used.
+func HTMLRenderer(t *template.Template) ErrorRenderer {
+	if t == nil {
+		t = DefaultErrorTemplate
+	}
+
+	return ErrorRendererFunc(func(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+		writeHeader(w, "text/html; charset=utf-8", info.Status)
+		t.Execute(w, info)
+	})
+}
+
+// Negotiate returns a renderer that chooses among the renderers, keyed by
+// media type, the one that is most preferred by the request Accept header.
+// The fallback is used when the header is missing, none of the media types
+// are acceptable, or they are only matched by the '*/*' range.
+func Negotiate(renderers map[string]ErrorRenderer, fallback ErrorRenderer) ErrorRenderer {
+	types := make([]string, 0, len(renderers))
+	for t := range renderers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return ErrorRendererFunc(func(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+		if t := negotiate(r.Header.Get("Accept"), types); t != "" {
+			renderers[t].Render(w, r, info)
+		} else {
+			fallback.Render(w, r, info)
+		}
+	})
+}
+
+func renderText(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+	writeHeader(w, "text/plain; charset=utf-8", info.Status)
+
+	if info.Detail != "" {
+		w.Write([]byte(info.Detail))
+	} else {
+		w.Write([]byte(info.Title))
+	}
+}
+
+func renderJSON(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+	writeHeader(w, "application/json", info.Status)
+
+	json.NewEncoder(w).Encode(struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+		Detail string `json:"detail,omitempty"`
+	}{info.Status, info.Title, info.Detail})
+}
+
+func renderProblem(w http.ResponseWriter, r *http.Request, info PanicInfo) {
+	writeHeader(w, "application/problem+json", info.Status)
+
+	json.NewEncoder(w).Encode(struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+		Detail string `json:"detail,omitempty"`
+	}{"about:blank", info.Title, info.Status, info.Detail})
+}
+
+func writeHeader(w http.ResponseWriter, contentType string, status int) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+}
+
+// negotiate returns the media type, out of the sorted types, with the
+// highest quality in the Accept header, preferring types matched by more
+// specific ranges on ties. Types only matched by the '*/*' range are not
+// considered. It returns an empty string if none of the types are acceptable.
+func negotiate(accept string, types []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return ""
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		i := strings.IndexByte(mt, '/')
+		if i == -1 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{mt[:i], mt[i+1:], q})
+	}
+
+	best, bestQ, bestSpecificity := "", 0.0, 0
+	for _, t := range types {
+		i := strings.IndexByte(t, '/')
+		if i == -1 {
+			continue
+		}
+		typ, subtype := t[:i], t[i+1:]
+
+		q, specificity := 0.0, 0
+		for _, r := range ranges {
+			s := 0
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 3
+			case r.typ == typ && r.subtype == "*":
+				s = 2
+			case r.typ == "*" && r.subtype == "*":
+				s = 1
+			}
+
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+
+		if specificity < 2 {
+			continue
+		}
+
+		if q > bestQ || q == bestQ && q > 0 && specificity > bestSpecificity {
+			best, bestQ, bestSpecificity = t, q, specificity
+		}
+	}
+
+	return best
+}
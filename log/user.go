@@ -0,0 +1,84 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/urandom/handler"
+)
+
+// UserResolver determines the authenticated user that made a request.
+type UserResolver interface {
+	// RemoteUser returns the user name, or an empty string if it's unknown.
+	RemoteUser(r *http.Request) string
+}
+
+// The UserResolverFunc type is an adapter to allow using ordinary functions
+// as user resolvers.
+type UserResolverFunc func(r *http.Request) string
+
+func (f UserResolverFunc) RemoteUser(r *http.Request) string {
+	return f(r)
+}
+
+// BasicAuthUser resolves the user name from the Basic Authorization header.
+// It is used by default. Other authentication handlers may record the user
+// using SetUser, as auth.RequireToken does with the subject of the JWT
+// claims.
+var BasicAuthUser UserResolver = UserResolverFunc(basicAuthUser)
+
+// RemoteUser defines the resolvers used to determine the user of a request.
+// They are tried in order, after the request has been served, and the first
+// non-empty result is used. A user recorded via SetUser always takes
+// precedence.
+func RemoteUser(resolvers ...UserResolver) Option {
+	return Option{func(o *options) {
+		o.users = resolvers
+	}}
+}
+
+// ContextUser returns a resolver that reads the user from the request
+// context value stored under key. The value may be a string, or a
+// fmt.Stringer.
+func ContextUser(key interface{}) UserResolver {
+	return UserResolverFunc(func(r *http.Request) string {
+		switch v := r.Context().Value(key).(type) {
+		case string:
+			return v
+		case fmt.Stringer:
+			return v.String()
+		}
+
+		return ""
+	})
+}
+
+// SetUser records the user for the access log message of the current
+// request. It is intended for handlers further down the chain, which
+// authenticate the user themselves. It has no effect if the request isn't
+// served by an Access handler.
+func SetUser(r *http.Request, user string) {
+	handler.SetUser(r, user)
+}
+
+func remoteUser(r *http.Request, resolvers []UserResolver) string {
+	if user := handler.User(r); user != "" {
+		return user
+	}
+
+	for _, res := range resolvers {
+		if user := res.RemoteUser(r); user != "" {
+			return user
+		}
+	}
+
+	return ""
+}
+
+func basicAuthUser(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+
+	return ""
+}
@@ -0,0 +1,68 @@
+package log_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urandom/handler/log"
+)
+
+type userKey struct{}
+
+func TestAccessRemoteUser(t *testing.T) {
+	cases := []struct {
+		inner func(r *http.Request)
+		setup func(r *http.Request) *http.Request
+		opts  []log.Option
+		user  string
+	}{
+		{
+			setup: func(r *http.Request) *http.Request { r.SetBasicAuth("basic-user", "pass"); return r },
+			user:  "basic-user",
+		},
+		{
+			setup: func(r *http.Request) *http.Request { r.Header.Set("Authorization", "Bearer token"); return r },
+			user:  "-",
+		},
+		{
+			setup: func(r *http.Request) *http.Request { r.SetBasicAuth("basic-user", "pass"); return r },
+			opts: []log.Option{log.RemoteUser(log.UserResolverFunc(func(r *http.Request) string {
+				return r.Header.Get("X-User")
+			}), log.BasicAuthUser)},
+			user: "basic-user",
+		},
+		{
+			setup: func(r *http.Request) *http.Request {
+				return r.WithContext(context.WithValue(r.Context(), userKey{}, "context-user"))
+			},
+			opts: []log.Option{log.RemoteUser(log.ContextUser(userKey{}))},
+			user: "context-user",
+		},
+		{
+			inner: func(r *http.Request) { log.SetUser(r, "downstream-user") },
+			setup: func(r *http.Request) *http.Request { r.SetBasicAuth("basic-user", "pass"); return r },
+			user:  "downstream-user",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			l := &logger{}
+			h := log.Access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.inner != nil {
+					tc.inner(r)
+				}
+			}), append(tc.opts, log.Logger(l), log.Format("%u"))...)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			h.ServeHTTP(httptest.NewRecorder(), tc.setup(r))
+
+			if l.message != tc.user {
+				t.Fatalf("expected user %s, got %s", tc.user, l.message)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"bytes"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // A Logger interface is used by handlers to when some kind of output needs to be provided
@@ -40,3 +44,97 @@ type errLogger struct{}
 func (l errLogger) Print(v ...interface{}) {
 	fmt.Fprint(os.Stderr, v...)
 }
+
+// Level is the severity of a structured log message. The values match the
+// ones used by the log/slog package.
+type Level int
+
+const (
+	// LevelDebug is used for verbose, diagnostic messages.
+	LevelDebug Level = -4
+	// LevelInfo is used for informational messages, such as access logs.
+	LevelInfo Level = 0
+	// LevelWarn is used for messages about unexpected, but handled, events.
+	LevelWarn Level = 4
+	// LevelError is used for messages about failures.
+	LevelError Level = 8
+)
+
+// Field is a key/value pair attached to a structured log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// A StructuredLogger interface is used by handlers to output leveled
+// messages, along with key/value fields describing them.
+type StructuredLogger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// StructuredFromLogger returns a StructuredLogger that prints each message
+// as a single line to the Logger l, in the following format:
+//
+// LEVEL MESSAGE KEY=VALUE KEY="QUOTED VALUE"
+func StructuredFromLogger(l Logger) StructuredLogger {
+	return structuredLogger{l}
+}
+
+// Structured returns s, if it is not nil, or a StructuredLogger that prints
+// to the Logger l otherwise, as returned by StructuredFromLogger. It is used
+// by handlers that accept both kinds of loggers, with s taking precedence.
+func Structured(s StructuredLogger, l Logger) StructuredLogger {
+	if s != nil {
+		return s
+	}
+
+	return StructuredFromLogger(l)
+}
+
+// RequestFields returns the fields that describe a request: its method and
+// path.
+func RequestFields(r *http.Request) []Field {
+	return []Field{
+		{Key: "method", Value: r.Method},
+		{Key: "path", Value: r.URL.Path},
+	}
+}
+
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+type structuredLogger struct {
+	logger Logger
+}
+
+func (l structuredLogger) Log(level Level, msg string, fields ...Field) {
+	var buf bytes.Buffer
+
+	buf.WriteString(level.String())
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+
+	for _, f := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+
+		v := fmt.Sprint(f.Value)
+		if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+			v = strconv.Quote(v)
+		}
+		buf.WriteString(v)
+	}
+
+	l.logger.Print(buf.String())
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package handler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger returns a StructuredLogger that outputs its messages via the
+// slog.Logger l.
+func SlogLogger(l *slog.Logger) StructuredLogger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Log(level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+
+	l.logger.LogAttrs(context.Background(), slog.Level(level), msg, attrs...)
+}
@@ -0,0 +1,30 @@
+//go:build go1.21
+// +build go1.21
+
+package handler_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := handler.SlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	l.Log(handler.LevelDebug, "hidden")
+	l.Log(handler.LevelError, "test message", handler.Field{Key: "status", Value: 500})
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("debug message was not filtered out: %s", out)
+	}
+
+	if !strings.Contains(out, `level=ERROR msg="test message" status=500`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+type printLogger struct {
+	message string
+}
+
+func (l *printLogger) Print(v ...interface{}) {
+	l.message = fmt.Sprint(v...)
+}
+
+func TestStructuredFromLogger(t *testing.T) {
+	l := &printLogger{}
+
+	handler.StructuredFromLogger(l).Log(handler.LevelWarn, "test message",
+		handler.Field{Key: "status", Value: 404},
+		handler.Field{Key: "path", Value: "/foo bar"},
+		handler.Field{Key: "user", Value: ""},
+		handler.Field{Key: "error", Value: errors.New("not found")},
+	)
+
+	expected := `WARN test message status=404 path="/foo bar" user="" error="not found"`
+	if l.message != expected {
+		t.Fatalf("expected %s, got %s", expected, l.message)
+	}
+}
+
+type fieldLogger struct {
+	msg string
+}
+
+func (l *fieldLogger) Log(level handler.Level, msg string, fields ...handler.Field) {
+	l.msg = msg
+}
+
+func TestStructured(t *testing.T) {
+	l := &printLogger{}
+	s := &fieldLogger{}
+
+	handler.Structured(s, l).Log(handler.LevelError, "structured")
+	if s.msg != "structured" || l.message != "" {
+		t.Fatalf("expected the structured logger to be used, got %q and %q", s.msg, l.message)
+	}
+
+	handler.Structured(nil, l).Log(handler.LevelError, "plain")
+	if l.message != "ERROR plain" {
+		t.Fatalf("expected the plain logger to be used, got %q", l.message)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package metrics provides a handler that records HTTP request metrics, and
+serves them in the Prometheus text exposition format.
+*/
+package metrics
@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/urandom/handler"
+)
+
+var (
+	// DefaultBuckets are the default latency histogram buckets, in seconds.
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are the default response size histogram buckets,
+	// in bytes.
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+)
+
+type options struct {
+	namespace   string
+	buckets     []float64
+	sizeBuckets []float64
+	route       func(r *http.Request) string
+}
+
+// An Option is used to change the default behaviour of the metrics.
+type Option struct {
+	f func(o *options)
+}
+
+// Namespace sets the prefix of the metric names. The default is 'http'.
+func Namespace(ns string) Option {
+	return Option{func(o *options) {
+		o.namespace = ns
+	}}
+}
+
+// Buckets sets the upper bounds, in seconds, of the request duration
+// histogram buckets.
+func Buckets(b ...float64) Option {
+	return Option{func(o *options) {
+		o.buckets = b
+	}}
+}
+
+// SizeBuckets sets the upper bounds, in bytes, of the response size
+// histogram buckets.
+func SizeBuckets(b ...float64) Option {
+	return Option{func(o *options) {
+		o.sizeBuckets = b
+	}}
+}
+
+// RouteFunc sets the function that provides the value of the 'route' label
+// of a request. It should return a route pattern, such as '/users/:id',
+// rather than the request path, to keep the number of series bounded. By
+// default, the label is empty.
+func RouteFunc(f func(r *http.Request) string) Option {
+	return Option{func(o *options) {
+		o.route = f
+	}}
+}
+
+// Metrics records the number of requests, the number of requests in flight,
+// and histograms of the request durations and response sizes. Apart from the
+// in-flight gauge, all metrics are labelled by the request method, the
+// response status class, such as '2xx', and the route.
+//
+// Metrics is an http.Handler, serving the recorded metrics in the
+// Prometheus text exposition format.
+type Metrics struct {
+	o        options
+	inFlight int64
+
+	mu     sync.Mutex
+	series map[labels]*series
+}
+
+type labels struct {
+	method, status, route string
+}
+
+type series struct {
+	duration histogram
+	size     histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// New creates a new, empty, set of metrics.
+func New(opts ...Option) *Metrics {
+	o := options{
+		namespace:   "http",
+		buckets:     DefaultBuckets,
+		sizeBuckets: DefaultSizeBuckets,
+		route:       func(r *http.Request) string { return "" },
+	}
+	o.apply(opts)
+
+	o.buckets = sortedBuckets(o.buckets)
+	o.sizeBuckets = sortedBuckets(o.sizeBuckets)
+
+	return &Metrics{o: o, series: map[labels]*series{}}
+}
+
+// Handler returns a handler that records the metrics of each request served
+// by the handler h.
+func (m *Metrics) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&m.inFlight, 1)
+		defer atomic.AddInt64(&m.inFlight, -1)
+
+		start := time.Now()
+		wrapper := handler.NewStreamWrapper(w)
+
+		h.ServeHTTP(wrapper, r)
+
+		m.observe(labels{
+			method: method(r.Method),
+			status: strconv.Itoa(wrapper.Status()/100) + "xx",
+			route:  m.o.route(r),
+		}, time.Since(start), wrapper.Written)
+	})
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(m.expose()))
+}
+
+func (m *Metrics) observe(l labels, d time.Duration, size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[l]
+	if !ok {
+		s = &series{
+			duration: histogram{counts: make([]uint64, len(m.o.buckets))},
+			size:     histogram{counts: make([]uint64, len(m.o.sizeBuckets))},
+		}
+		m.series[l] = s
+	}
+
+	s.duration.observe(m.o.buckets, d.Seconds())
+	s.size.observe(m.o.sizeBuckets, float64(size))
+}
+
+func (m *Metrics) expose() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]labels, 0, len(m.series))
+	for l := range m.series {
+		keys = append(keys, l)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.status < b.status
+	})
+
+	ns := m.o.namespace
+	var b strings.Builder
+
+	header(&b, ns+"_requests_total", "counter", "Total number of HTTP requests.")
+	for _, l := range keys {
+		sample(&b, ns+"_requests_total", l.String(""), float64(m.series[l].duration.count))
+	}
+
+	header(&b, ns+"_requests_in_flight", "gauge", "Number of HTTP requests currently being served.")
+	sample(&b, ns+"_requests_in_flight", "", float64(atomic.LoadInt64(&m.inFlight)))
+
+	header(&b, ns+"_request_duration_seconds", "histogram", "Duration of HTTP requests in seconds.")
+	for _, l := range keys {
+		m.series[l].duration.write(&b, ns+"_request_duration_seconds", l, m.o.buckets)
+	}
+
+	header(&b, ns+"_response_size_bytes", "histogram", "Size of HTTP response bodies in bytes.")
+	for _, l := range keys {
+		m.series[l].size.write(&b, ns+"_response_size_bytes", l, m.o.sizeBuckets)
+	}
+
+	return b.String()
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	h.sum += v
+	h.count++
+
+	// Counts are not cumulative, the exposition adds them up
+	for i, upper := range buckets {
+		if v <= upper {
+			h.counts[i]++
+			return
+		}
+	}
+}
+
+func (h *histogram) write(b *strings.Builder, name string, l labels, buckets []float64) {
+	var cumulative uint64
+	for i, upper := range buckets {
+		cumulative += h.counts[i]
+		sample(b, name+"_bucket", l.String(formatFloat(upper)), float64(cumulative))
+	}
+
+	// Values above the last bucket are only counted in +Inf
+	sample(b, name+"_bucket", l.String("+Inf"), float64(h.count))
+	sample(b, name+"_sum", l.String(""), h.sum)
+	sample(b, name+"_count", l.String(""), float64(h.count))
+}
+
+// String returns the label set in the exposition format, with an optional
+// 'le' label for histogram buckets.
+func (l labels) String(le string) string {
+	s := `{method="` + escape(l.method) + `",route="` + escape(l.route) + `",status="` + escape(l.status) + `"`
+	if le != "" {
+		s += `,le="` + le + `"`
+	}
+
+	return s + "}"
+}
+
+func header(b *strings.Builder, name, typ, help string) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func sample(b *strings.Builder, name, labels string, v float64) {
+	b.WriteString(name + labels + " " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes a label value, as required by the exposition format.
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// method limits the method label to the standard methods, to keep the
+// number of series bounded.
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	default:
+		return "OTHER"
+	}
+}
+
+func sortedBuckets(b []float64) []float64 {
+	sorted := make([]float64, len(b))
+	copy(sorted, b)
+	sort.Float64s(sorted)
+
+	return sorted
+}
+
+func (o *options) apply(opts []Option) {
+	for _, op := range opts {
+		op.f(o)
+	}
+}
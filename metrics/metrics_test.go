@@ -0,0 +1,137 @@
+package metrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/urandom/handler/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New(
+		metrics.Buckets(1, 0.001),
+		metrics.SizeBuckets(10, 100),
+		metrics.RouteFunc(func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/users/") {
+				return "/users/:id"
+			}
+			return r.URL.Path
+		}),
+	)
+
+	inFlight := ""
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1", "/users/2":
+			w.Write([]byte("0123456789abcdef"))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/metrics":
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, r)
+			inFlight = rec.Body.String()
+		}
+	}))
+
+	requests := []struct {
+		method, path string
+	}{
+		{"GET", "/users/1"},
+		{"GET", "/users/2"},
+		{"GET", "/missing"},
+		{"PURGE", "/missing"},
+		{"GET", "/metrics"},
+	}
+
+	var wg sync.WaitGroup
+	for _, req := range requests {
+		wg.Add(1)
+		go func(method, path string) {
+			defer wg.Done()
+
+			r, _ := http.NewRequest(method, "http://localhost:8080"+path, nil)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}(req.method, req.path)
+	}
+	wg.Wait()
+
+	if !regexp.MustCompile(`\nhttp_requests_in_flight [1-5]\n`).MatchString(inFlight) {
+		t.Fatalf("expected the exposition request to be in flight, got %s", inFlight)
+	}
+
+	rec := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://localhost:8080/metrics", nil)
+	m.ServeHTTP(rec, r)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+
+	body := rec.Body.String()
+	expected := []string{
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{method="GET",route="/users/:id",status="2xx"} 2` + "\n",
+		`http_requests_total{method="GET",route="/missing",status="4xx"} 1` + "\n",
+		`http_requests_total{method="OTHER",route="/missing",status="4xx"} 1` + "\n",
+		"# TYPE http_requests_in_flight gauge\nhttp_requests_in_flight 0\n",
+		"# TYPE http_request_duration_seconds histogram\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="1"} 2` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="+Inf"} 2` + "\n",
+		`http_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2` + "\n",
+		`http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="10"} 0` + "\n",
+		`http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="100"} 2` + "\n",
+		`http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="+Inf"} 2` + "\n",
+		`http_response_size_bytes_sum{method="GET",route="/users/:id",status="2xx"} 32` + "\n",
+		`http_response_size_bytes_bucket{method="GET",route="/missing",status="4xx",le="10"} 1` + "\n",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Fatalf("expected exposition to contain %q, got %s", e, body)
+		}
+	}
+
+	// Buckets are sorted
+	if strings.Index(body, `le="0.001"`) > strings.Index(body, `le="1"`) {
+		t.Fatalf("expected sorted buckets, got %s", body)
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	cases := []struct {
+		route    string
+		code     int
+		expected string
+	}{
+		{"", 200, `myapp_requests_total{method="GET",route="",status="2xx"} 1`},
+		{`quote"back\slash`, 503, `myapp_requests_total{method="GET",route="quote\"back\\slash",status="5xx"} 1`},
+		{"new\nline", 301, `myapp_requests_total{method="GET",route="new\nline",status="3xx"} 1`},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			m := metrics.New(metrics.Namespace("myapp"), metrics.RouteFunc(func(r *http.Request) string {
+				return tc.route
+			}))
+
+			h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.code)
+			}))
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, r)
+
+			if !strings.Contains(rec.Body.String(), tc.expected+"\n") {
+				t.Fatalf("expected exposition to contain %s, got %s", tc.expected, rec.Body.String())
+			}
+		})
+	}
+}
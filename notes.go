@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type notesKey struct{}
+
+// notes holds the values recorded by handlers further down the chain, which
+// can't modify the request seen by the handlers wrapping them.
+type notes struct {
+	mu     sync.Mutex
+	values map[string]string
+	user   string
+}
+
+// WithNotes returns a request, in which handlers further down the chain may
+// record notes using SetNote and SetUser, to be read back with Note and
+// User once they have served it. If r already carries notes, it is returned
+// as is, so that the notes are shared with the handlers wrapping this one.
+func WithNotes(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(notesKey{}).(*notes); ok {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), notesKey{}, &notes{values: map[string]string{}}))
+}
+
+// SetNote records a named value for the current request. It has no effect if
+// the request wasn't prepared using WithNotes.
+func SetNote(r *http.Request, name, value string) {
+	if n, ok := r.Context().Value(notesKey{}).(*notes); ok {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		n.values[name] = value
+	}
+}
+
+// Note returns the named value recorded for the request, and whether there
+// was one.
+func Note(r *http.Request, name string) (string, bool) {
+	if n, ok := r.Context().Value(notesKey{}).(*notes); ok {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		v, ok := n.values[name]
+		return v, ok
+	}
+
+	return "", false
+}
+
+// SetUser records the authenticated user of the current request, as
+// determined by a handler further down the chain. It has no effect if the
+// request wasn't prepared using WithNotes.
+func SetUser(r *http.Request, user string) {
+	if n, ok := r.Context().Value(notesKey{}).(*notes); ok {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		n.user = user
+	}
+}
+
+// User returns the user recorded for the request, or an empty string.
+func User(r *http.Request) string {
+	if n, ok := r.Context().Value(notesKey{}).(*notes); ok {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		return n.user
+	}
+
+	return ""
+}
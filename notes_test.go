@@ -0,0 +1,46 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+func TestNotes(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+
+	// No effect without notes
+	handler.SetNote(r, "key", "value")
+	handler.SetUser(r, "user")
+
+	if _, ok := handler.Note(r, "key"); ok {
+		t.Fatalf("expected no note")
+	}
+
+	if u := handler.User(r); u != "" {
+		t.Fatalf("expected no user, got %q", u)
+	}
+
+	outer := handler.WithNotes(r)
+	inner := handler.WithNotes(outer)
+	if inner != outer {
+		t.Fatalf("expected the existing notes to be reused")
+	}
+
+	// Notes set on a derived request are visible to the original
+	handler.SetNote(inner.WithContext(inner.Context()), "key", "value")
+	handler.SetUser(inner.WithContext(inner.Context()), "user")
+
+	if v, ok := handler.Note(outer, "key"); !ok || v != "value" {
+		t.Fatalf("expected note %q, got %q", "value", v)
+	}
+
+	if _, ok := handler.Note(outer, "other"); ok {
+		t.Fatalf("expected no other note")
+	}
+
+	if u := handler.User(outer); u != "user" {
+		t.Fatalf("expected user %q, got %q", "user", u)
+	}
+}
@@ -1,9 +1,92 @@
 package handler
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrSessionUnsupported is returned by the session helpers when the
+	// Session doesn't implement the requested operation.
+	ErrSessionUnsupported = errors.New("session: operation not supported")
+
+	// ErrSessionValueNotFound is returned by GetSessionValue when the
+	// session doesn't contain the requested key.
+	ErrSessionValueNotFound = errors.New("session: value not found")
+)
 
 // Session allows storing arbitrary data in between requests.
 type Session interface {
 	Get(r *http.Request, key string) (string, error)
 	Set(r *http.Request, key string, value string) error
 }
+
+// ExtendedSession is a Session that can also remove and list its values.
+// Consumers should use the DeleteSessionValue, SessionKeys and ClearSession
+// helpers, which accept any Session and use these methods when available.
+type ExtendedSession interface {
+	Session
+
+	// Delete removes the value stored under key.
+	Delete(r *http.Request, key string) error
+	// Keys returns the keys of all stored values.
+	Keys(r *http.Request) ([]string, error)
+	// Clear removes all stored values.
+	Clear(r *http.Request) error
+}
+
+// DeleteSessionValue removes the value stored under key. If the session
+// isn't an ExtendedSession, the value is set to an empty string instead.
+func DeleteSessionValue(s Session, r *http.Request, key string) error {
+	if es, ok := s.(ExtendedSession); ok {
+		return es.Delete(r, key)
+	}
+
+	return s.Set(r, key, "")
+}
+
+// SessionKeys returns the keys of all values stored in the session. If the
+// session isn't an ExtendedSession, ErrSessionUnsupported is returned.
+func SessionKeys(s Session, r *http.Request) ([]string, error) {
+	if es, ok := s.(ExtendedSession); ok {
+		return es.Keys(r)
+	}
+
+	return nil, ErrSessionUnsupported
+}
+
+// ClearSession removes all values stored in the session. If the session isn't
+// an ExtendedSession, ErrSessionUnsupported is returned.
+func ClearSession(s Session, r *http.Request) error {
+	if es, ok := s.(ExtendedSession); ok {
+		return es.Clear(r)
+	}
+
+	return ErrSessionUnsupported
+}
+
+// GetSessionValue decodes the JSON-encoded value stored under key into v. If
+// there is no such value, ErrSessionValueNotFound is returned.
+func GetSessionValue(s Session, r *http.Request, key string, v interface{}) error {
+	val, err := s.Get(r, key)
+	if err != nil {
+		return err
+	}
+
+	if val == "" {
+		return ErrSessionValueNotFound
+	}
+
+	return json.Unmarshal([]byte(val), v)
+}
+
+// SetSessionValue stores v under key, encoded as JSON.
+func SetSessionValue(s Session, r *http.Request, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.Set(r, key, string(b))
+}
@@ -0,0 +1,289 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCookieSize is the largest cookie value most browsers will accept.
+const maxCookieSize = 4096
+
+var (
+	// ErrInvalidCookie is returned when a session cookie cannot be verified
+	// or decrypted with any of the provided keys.
+	ErrInvalidCookie = errors.New("session: invalid cookie")
+
+	// ErrExpiredCookie is returned when a session cookie is older than the
+	// configured max age.
+	ErrExpiredCookie = errors.New("session: expired cookie")
+
+	// ErrCookieTooLong is returned when the encoded session data doesn't fit
+	// in a single cookie.
+	ErrCookieTooLong = errors.New("session: encoded cookie value is too long")
+
+	// ErrNoKeys is returned when a cookie session is created without any
+	// keys.
+	ErrNoKeys = errors.New("session: no keys provided")
+)
+
+// Key contains the secrets used to protect the cookie value.
+type Key struct {
+	// Hash is used to authenticate the cookie value using HMAC-SHA256. It
+	// is required, and should be at least 32 bytes long.
+	Hash []byte
+
+	// Block, if set, is used to encrypt the cookie value using AES-GCM. It
+	// must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or
+	// AES-256.
+	Block []byte
+}
+
+// CookieSession is a handler.Session that keeps all of its values in a
+// signed, and optionally encrypted, cookie.
+type CookieSession struct {
+	o      options
+	codecs []codec
+	key    contextKey
+}
+
+type codec struct {
+	hash []byte
+	aead cipher.AEAD
+}
+
+// NewCookieSession creates a new cookie session. The first key is used to
+// encode new cookies, while all of the keys are tried when decoding one. This
+// allows rotating keys by prepending a new one, and dropping the oldest one
+// once all cookies encoded with it have expired.
+func NewCookieSession(keys []Key, opts ...Option) (*CookieSession, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	o := defaultOptions()
+	o.apply(opts)
+
+	s := &CookieSession{o: o}
+	s.key = contextKey{s}
+
+	for _, k := range keys {
+		if len(k.Hash) == 0 {
+			return nil, errors.New("session: empty hash key")
+		}
+
+		c := codec{hash: k.Hash}
+		if len(k.Block) > 0 {
+			block, err := aes.NewCipher(k.Block)
+			if err != nil {
+				return nil, err
+			}
+
+			if c.aead, err = cipher.NewGCM(block); err != nil {
+				return nil, err
+			}
+		}
+
+		s.codecs = append(s.codecs, c)
+	}
+
+	return s, nil
+}
+
+// Get returns the value stored under key in the request's session.
+func (s *CookieSession) Get(r *http.Request, key string) (string, error) {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return "", err
+	}
+
+	return st.get(key), nil
+}
+
+// Set stores the value under key in the request's session. The change will
+// be sent to the client before the response header is written.
+func (s *CookieSession) Set(r *http.Request, key string, value string) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.set(key, value)
+}
+
+// Delete removes the value stored under key in the request's session.
+func (s *CookieSession) Delete(r *http.Request, key string) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.delete(key)
+}
+
+// Keys returns the sorted keys of all values in the request's session.
+func (s *CookieSession) Keys(r *http.Request) ([]string, error) {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.keys(), nil
+}
+
+// Clear removes all values from the request's session.
+func (s *CookieSession) Clear(r *http.Request) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.clear()
+}
+
+// Handler returns a handler that loads the session from the request cookie
+// before invoking handler h, and stores any modifications in the response.
+// Invalid or expired cookies are treated as an empty session.
+func (s *CookieSession) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := &state{values: map[string]string{}}
+
+		if c, err := r.Cookie(s.o.name); err == nil {
+			values, stale, err := s.decode(c.Value, time.Now())
+			if err == nil {
+				st.values = values
+				// Re-encode cookies signed with a rotated key
+				st.dirty = stale
+			} else {
+				s.o.logError("session handler", err)
+				// Remove the broken cookie
+				st.dirty = true
+			}
+		}
+
+		serve(h, w, r, s.key, st, s.commit, nil)
+	})
+}
+
+func (s *CookieSession) commit(w http.ResponseWriter, st *state) {
+	if !st.dirty {
+		return
+	}
+
+	if len(st.values) == 0 {
+		http.SetCookie(w, s.o.cookie(""))
+		return
+	}
+
+	value, err := s.encode(st.values, time.Now())
+	if err != nil {
+		s.o.logError("session handler", err)
+		return
+	}
+
+	http.SetCookie(w, s.o.cookie(value))
+}
+
+func (s *CookieSession) encode(values map[string]string, now time.Time) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	c := s.codecs[0]
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+
+		data = c.aead.Seal(nonce, nonce, data, []byte(s.o.name))
+	}
+
+	payload := strconv.FormatInt(now.Unix(), 10) + "|" + base64.RawURLEncoding.EncodeToString(data)
+	value := base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + string(c.mac(s.o.name, payload))))
+
+	if len(value) > maxCookieSize {
+		return "", ErrCookieTooLong
+	}
+
+	return value, nil
+}
+
+// decode returns the session values stored in the cookie value, and whether
+// it was encoded with one of the older keys.
+func (s *CookieSession) decode(value string, now time.Time) (map[string]string, bool, error) {
+	if len(value) > maxCookieSize {
+		return nil, false, ErrCookieTooLong
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false, ErrInvalidCookie
+	}
+
+	// The mac may contain the separator, but the timestamp and the encoded
+	// data can't.
+	parts := strings.SplitN(string(decoded), "|", 3)
+	if len(parts) != 3 {
+		return nil, false, ErrInvalidCookie
+	}
+
+	payload := parts[0] + "|" + parts[1]
+	for i, c := range s.codecs {
+		if !hmac.Equal([]byte(parts[2]), c.mac(s.o.name, payload)) {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, false, ErrInvalidCookie
+		}
+
+		if s.o.maxAge > 0 && now.Unix()-ts > int64(s.o.maxAge) {
+			return nil, false, ErrExpiredCookie
+		}
+
+		data, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, false, ErrInvalidCookie
+		}
+
+		if c.aead != nil {
+			size := c.aead.NonceSize()
+			if len(data) < size {
+				return nil, false, ErrInvalidCookie
+			}
+
+			if data, err = c.aead.Open(nil, data[:size], data[size:], []byte(s.o.name)); err != nil {
+				return nil, false, ErrInvalidCookie
+			}
+		}
+
+		values := map[string]string{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, false, ErrInvalidCookie
+		}
+
+		return values, i > 0, nil
+	}
+
+	return nil, false, ErrInvalidCookie
+}
+
+func (c codec) mac(name, payload string) []byte {
+	m := hmac.New(sha256.New, c.hash)
+	m.Write([]byte(name + "|" + payload))
+
+	return m.Sum(nil)
+}
@@ -0,0 +1,188 @@
+package session_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urandom/handler"
+	"github.com/urandom/handler/session"
+)
+
+var (
+	hashKey  = []byte("0123456789abcdef0123456789abcdef")
+	blockKey = []byte("abcdef0123456789")
+)
+
+func TestCookieSession(t *testing.T) {
+	cases := []struct {
+		keys []session.Key
+	}{
+		{[]session.Key{{Hash: hashKey}}},
+		{[]session.Key{{Hash: hashKey, Block: blockKey}}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			s, err := session.NewCookieSession(tc.keys, session.Secure(true), session.Domain("example.com"))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "POST" {
+					if err := s.Set(r, "user", "frank"); err != nil {
+						t.Fatalf("set: %s", err)
+					}
+				} else {
+					val, err := s.Get(r, "user")
+					if err != nil {
+						t.Fatalf("get: %s", err)
+					}
+					w.Write([]byte(val))
+				}
+			}))
+
+			rec := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", "http://localhost:8080", nil)
+			h.ServeHTTP(rec, r)
+
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("expected 1 cookie, got %d", len(cookies))
+			}
+
+			c := cookies[0]
+			if c.Name != "session" || c.Path != "/" || c.Domain != "example.com" || !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("unexpected cookie attributes: %#v", c)
+			}
+
+			rec = httptest.NewRecorder()
+			r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+			r.AddCookie(c)
+			h.ServeHTTP(rec, r)
+
+			if rec.Body.String() != "frank" {
+				t.Fatalf("expected session value %s, got %s", "frank", rec.Body.String())
+			}
+
+			if len(rec.Result().Cookies()) != 0 {
+				t.Fatalf("unmodified session was stored")
+			}
+
+			// Tampered cookie
+			rec = httptest.NewRecorder()
+			r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+			r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value[:len(c.Value)-2] + "AA"})
+			h.ServeHTTP(rec, r)
+
+			if rec.Body.String() != "" {
+				t.Fatalf("expected empty session value, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCookieSessionKeyRotation(t *testing.T) {
+	oldKey := session.Key{Hash: []byte("old hash key"), Block: blockKey}
+	newKey := session.Key{Hash: hashKey}
+
+	old, err := session.NewCookieSession([]session.Key{oldKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rotated, err := session.NewCookieSession([]session.Key{newKey, oldKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	old.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		old.Set(r, "user", "frank")
+	})).ServeHTTP(rec, r)
+
+	cookie := rec.Result().Cookies()[0]
+
+	rec = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+	r.AddCookie(cookie)
+	rotated.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val, _ := rotated.Get(r, "user")
+		w.Write([]byte(val))
+	})).ServeHTTP(rec, r)
+
+	if rec.Body.String() != "frank" {
+		t.Fatalf("expected session value %s, got %s", "frank", rec.Body.String())
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value == cookie.Value {
+		t.Fatalf("expected the cookie to be re-encoded with the new key")
+	}
+}
+
+func TestCookieSessionErrors(t *testing.T) {
+	if _, err := session.NewCookieSession(nil); err != session.ErrNoKeys {
+		t.Fatalf("expected %v, got %v", session.ErrNoKeys, err)
+	}
+
+	if _, err := session.NewCookieSession([]session.Key{{Hash: hashKey, Block: []byte("short")}}); err == nil {
+		t.Fatalf("expected an invalid block key error")
+	}
+
+	s, _ := session.NewCookieSession([]session.Key{{Hash: hashKey}})
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	if _, err := s.Get(r, "user"); err != session.ErrNoSession {
+		t.Fatalf("expected %v, got %v", session.ErrNoSession, err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		if err := s.Set(r, "user", "frank"); err != session.ErrCommitted {
+			t.Fatalf("expected %v, got %v", session.ErrCommitted, err)
+		}
+	})).ServeHTTP(rec, r)
+}
+
+func TestCookieSessionExtended(t *testing.T) {
+	s, _ := session.NewCookieSession([]session.Key{{Hash: hashKey}})
+
+	var _ handler.ExtendedSession = s
+
+	rec := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Set(r, "b", "2")
+		s.Set(r, "a", "1")
+
+		if keys, err := s.Keys(r); err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+			t.Fatalf("unexpected keys %v, error %v", keys, err)
+		}
+	})).ServeHTTP(rec, r)
+
+	cookie := rec.Result().Cookies()[0]
+
+	rec = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+	r.AddCookie(cookie)
+	s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Delete(r, "a"); err != nil {
+			t.Fatalf("delete: %s", err)
+		}
+
+		if err := s.Clear(r); err != nil {
+			t.Fatalf("clear: %s", err)
+		}
+	})).ServeHTTP(rec, r)
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Fatalf("expected the session cookie to be removed")
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package session provides implementations of the handler.Session interface,
+along with the handlers that load and save the session data for each request.
+*/
+package session
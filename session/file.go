@@ -0,0 +1,167 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const filePrefix = "session_"
+
+// ErrInvalidID is returned by the FileStore when a session ID contains
+// characters that are not safe for use in a file name.
+var ErrInvalidID = errors.New("session: invalid session id")
+
+// FileStore is a Store that keeps each record as a JSON file in a directory.
+type FileStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// NewFileStore creates a new file store in the given directory, creating it
+// if necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// Load returns the record stored under id.
+func (s *FileStore) Load(id string) (Record, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return Record{}, err
+	}
+
+	s.mu.RLock()
+	rec, err := readRecord(path)
+	s.mu.RUnlock()
+
+	if os.IsNotExist(err) {
+		return Record{}, ErrNotFound
+	} else if err != nil {
+		return Record{}, err
+	}
+
+	if rec.Expired(time.Now()) {
+		s.Delete(id)
+		return Record{}, ErrNotFound
+	}
+
+	return rec, nil
+}
+
+// Save stores the record under id. The file is replaced atomically, so that
+// concurrent loads never observe a partially written record.
+func (s *FileStore) Save(id string, rec Record) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := ioutil.TempFile(s.dir, ".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// Delete removes the record stored under id.
+func (s *FileStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Sweep removes all expired records, as well as any unreadable ones.
+func (s *FileStore) Sweep(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), filePrefix) {
+			continue
+		}
+
+		path := filepath.Join(s.dir, fi.Name())
+		if rec, err := readRecord(path); err != nil || rec.Expired(now) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" {
+		return "", ErrInvalidID
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return "", ErrInvalidID
+		}
+	}
+
+	return filepath.Join(s.dir, filePrefix+id), nil
+}
+
+func readRecord(path string) (Record, error) {
+	var rec Record
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+
+	err = json.Unmarshal(data, &rec)
+
+	return rec, err
+}
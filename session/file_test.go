@@ -0,0 +1,52 @@
+package session_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/urandom/handler/session"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := s.Save("../escape", session.Record{}); err != session.ErrInvalidID {
+		t.Fatalf("expected %v, got %v", session.ErrInvalidID, err)
+	}
+
+	if err := s.Save("a", session.Record{Values: map[string]string{"key": "a"}, Expires: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("save: %s", err)
+	}
+
+	if rec, err := s.Load("a"); err != nil || rec.Values["key"] != "a" {
+		t.Fatalf("unexpected record %v, error %v", rec, err)
+	}
+
+	if err := s.Sweep(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("sweep: %s", err)
+	}
+
+	if _, err := s.Load("a"); err != session.ErrNotFound {
+		t.Fatalf("expected the record to be swept, got %v", err)
+	}
+
+	s.Save("b", session.Record{})
+	if err := s.Delete("b"); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+
+	if _, err := s.Load("b"); err != session.ErrNotFound {
+		t.Fatalf("expected the record to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/urandom/handler"
+)
+
+// FlashLevel describes the severity of a flash message.
+type FlashLevel string
+
+const (
+	// FlashInfo is the level of informational messages.
+	FlashInfo FlashLevel = "info"
+	// FlashWarn is the level of warning messages.
+	FlashWarn FlashLevel = "warn"
+	// FlashError is the level of error messages.
+	FlashError FlashLevel = "error"
+)
+
+// FlashKey is the key under which the pending flash messages will be stored
+// in the session.
+var FlashKey = "flash"
+
+// Flash is a one-shot message, stored in the session until it is read.
+type Flash struct {
+	Level   FlashLevel `json:"level"`
+	Message string     `json:"message"`
+}
+
+// AddFlash appends a flash message to the request's session. Any
+// handler.Session implementation may be used.
+func AddFlash(s handler.Session, r *http.Request, level FlashLevel, message string) error {
+	var flashes []Flash
+	if err := handler.GetSessionValue(s, r, FlashKey, &flashes); err != nil && err != handler.ErrSessionValueNotFound {
+		return err
+	}
+
+	return handler.SetSessionValue(s, r, FlashKey, append(flashes, Flash{Level: level, Message: message}))
+}
+
+// Flashes returns all pending flash messages from the request's session, in
+// the order they were added, and removes them from it. Since this modifies
+// the session, it has to be called before the response header is written
+// when using a cookie session.
+func Flashes(s handler.Session, r *http.Request) ([]Flash, error) {
+	var flashes []Flash
+	if err := handler.GetSessionValue(s, r, FlashKey, &flashes); err != nil {
+		if err == handler.ErrSessionValueNotFound {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	return flashes, handler.DeleteSessionValue(s, r, FlashKey)
+}
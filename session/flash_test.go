@@ -0,0 +1,76 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urandom/handler/session"
+)
+
+func TestFlashes(t *testing.T) {
+	s, _ := session.NewCookieSession([]session.Key{{Hash: hashKey}})
+
+	var flashes []session.Flash
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			// Keeps the session alive once the flashes are removed
+			if err := s.Set(r, "user", "alice"); err != nil {
+				t.Fatalf("set: %s", err)
+			}
+
+			if err := session.AddFlash(s, r, session.FlashInfo, "Saved"); err != nil {
+				t.Fatalf("add flash: %s", err)
+			}
+
+			if err := session.AddFlash(s, r, session.FlashWarn, "Almost full"); err != nil {
+				t.Fatalf("add flash: %s", err)
+			}
+
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		} else {
+			var err error
+			if flashes, err = session.Flashes(s, r); err != nil {
+				t.Fatalf("flashes: %s", err)
+			}
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "http://localhost:8080", nil)
+	h.ServeHTTP(rec, r)
+
+	cookie := rec.Result().Cookies()[0]
+
+	rec = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+	r.AddCookie(cookie)
+	h.ServeHTTP(rec, r)
+
+	expected := []session.Flash{{session.FlashInfo, "Saved"}, {session.FlashWarn, "Almost full"}}
+	if len(flashes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, flashes)
+	}
+
+	for i := range expected {
+		if flashes[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected[i], flashes[i])
+		}
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge < 0 || cookies[0].Value == cookie.Value {
+		t.Fatalf("expected the session to be rewritten without the flashes")
+	}
+
+	// Reading again, with the updated session, yields nothing
+	flashes = nil
+	rec = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "http://localhost:8080", nil)
+	r.AddCookie(cookies[0])
+	h.ServeHTTP(rec, r)
+
+	if len(flashes) != 0 {
+		t.Fatalf("expected no flashes, got %v", flashes)
+	}
+}
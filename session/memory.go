@@ -0,0 +1,120 @@
+package session
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store. When it reaches its capacity, the least
+// recently used records are evicted to make room for new ones.
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	records  map[string]*list.Element
+}
+
+type memoryEntry struct {
+	id  string
+	rec Record
+}
+
+// NewMemoryStore creates a new in-memory store that holds at most capacity
+// records. A capacity of 0 or less means that the store is unbounded.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{
+		capacity: capacity,
+		order:    list.New(),
+		records:  map[string]*list.Element{},
+	}
+}
+
+// Load returns the record stored under id.
+func (s *MemoryStore) Load(id string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if entry.rec.Expired(time.Now()) {
+		s.remove(el)
+		return Record{}, ErrNotFound
+	}
+
+	s.order.MoveToFront(el)
+
+	rec := entry.rec
+	rec.Values = copyValues(rec.Values)
+
+	return rec, nil
+}
+
+// Save stores the record under id, evicting the least recently used record
+// if the store is full.
+func (s *MemoryStore) Save(id string, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.Values = copyValues(rec.Values)
+
+	if el, ok := s.records[id]; ok {
+		el.Value.(*memoryEntry).rec = rec
+		s.order.MoveToFront(el)
+
+		return nil
+	}
+
+	s.records[id] = s.order.PushFront(&memoryEntry{id: id, rec: rec})
+
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+// Delete removes the record stored under id.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.records[id]; ok {
+		s.remove(el)
+	}
+
+	return nil
+}
+
+// Sweep removes all expired records.
+func (s *MemoryStore) Sweep(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for el := s.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*memoryEntry).rec.Expired(now) {
+			s.remove(el)
+		}
+		el = next
+	}
+
+	return nil
+}
+
+// Len returns the number of records in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.records, el.Value.(*memoryEntry).id)
+}
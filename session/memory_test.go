@@ -0,0 +1,47 @@
+package session_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/urandom/handler/session"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := session.NewMemoryStore(2)
+
+	s.Save("a", session.Record{Values: map[string]string{"key": "a"}})
+	s.Save("b", session.Record{Values: map[string]string{"key": "b"}})
+
+	// Mark "a" as recently used, so that "b" gets evicted
+	if rec, err := s.Load("a"); err != nil || rec.Values["key"] != "a" {
+		t.Fatalf("unexpected record %v, error %v", rec, err)
+	}
+
+	s.Save("c", session.Record{Values: map[string]string{"key": "c"}})
+
+	if _, err := s.Load("b"); err != session.ErrNotFound {
+		t.Fatalf("expected the least recently used record to be evicted, got %v", err)
+	}
+
+	if s.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", s.Len())
+	}
+
+	s.Save("d", session.Record{Expires: time.Now().Add(-time.Second)})
+	if _, err := s.Load("d"); err != session.ErrNotFound {
+		t.Fatalf("expected an expired record to be missing, got %v", err)
+	}
+
+	s.Save("e", session.Record{Expires: time.Now().Add(time.Minute)})
+	s.Sweep(time.Now().Add(time.Hour))
+
+	if _, err := s.Load("e"); err != session.ErrNotFound {
+		t.Fatalf("expected the record to be swept, got %v", err)
+	}
+
+	s.Delete("a")
+	if _, err := s.Load("a"); err != session.ErrNotFound {
+		t.Fatalf("expected the record to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ServerSession is a handler.Session that keeps its values in a Store. The
+// session cookie only carries an opaque, random session ID.
+type ServerSession struct {
+	o     options
+	store Store
+	key   contextKey
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServerSession creates a new server-side session, backed by the given
+// store. Unless disabled with the SweepInterval option, expired records are
+// periodically removed from the store in the background, until the session
+// is closed.
+func NewServerSession(store Store, opts ...Option) *ServerSession {
+	o := defaultOptions()
+	o.apply(opts)
+
+	s := &ServerSession{o: o, store: store, done: make(chan struct{})}
+	s.key = contextKey{s}
+
+	if o.sweepInterval > 0 {
+		go s.sweep()
+	}
+
+	return s
+}
+
+// Get returns the value stored under key in the request's session.
+func (s *ServerSession) Get(r *http.Request, key string) (string, error) {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return "", err
+	}
+
+	return st.get(key), nil
+}
+
+// Set stores the value under key in the request's session.
+func (s *ServerSession) Set(r *http.Request, key string, value string) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.set(key, value)
+}
+
+// Delete removes the value stored under key in the request's session.
+func (s *ServerSession) Delete(r *http.Request, key string) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.delete(key)
+}
+
+// Keys returns the sorted keys of all values in the request's session.
+func (s *ServerSession) Keys(r *http.Request) ([]string, error) {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.keys(), nil
+}
+
+// Clear removes all values from the request's session.
+func (s *ServerSession) Clear(r *http.Request) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	return st.clear()
+}
+
+// Regenerate assigns a new ID to the request's session, keeping its values,
+// and removes the old one from the store. It should be called whenever the
+// privilege level of the user changes, such as after logging in, to protect
+// against session fixation. It has to be called before the response header
+// is written.
+func (s *ServerSession) Regenerate(r *http.Request) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.committed {
+		return ErrCommitted
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	if st.id != "" {
+		st.stale = append(st.stale, st.id)
+	} else {
+		st.created = time.Now()
+	}
+
+	st.id = id
+	st.regenerated = true
+
+	return nil
+}
+
+// Destroy removes all values of the request's session, as well as its record
+// in the store. If any values are set afterwards, a new session is created.
+func (s *ServerSession) Destroy(r *http.Request) error {
+	st, err := stateFromRequest(s.key, r)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.id != "" {
+		st.stale = append(st.stale, st.id)
+	}
+
+	st.id = ""
+	st.regenerated = false
+	st.values = map[string]string{}
+	st.dirty = true
+	st.late = false
+
+	return nil
+}
+
+// Close stops the background sweeping of expired records.
+func (s *ServerSession) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+
+	return nil
+}
+
+// Handler returns a handler that loads the session identified by the
+// request cookie from the store before invoking handler h. Afterwards, the
+// session is saved back to the store. A new session ID is only issued once
+// a value is set.
+func (s *ServerSession) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := &state{values: map[string]string{}}
+
+		if c, err := r.Cookie(s.o.name); err == nil {
+			st.cookie = true
+
+			if rec, err := s.store.Load(c.Value); err == nil {
+				if s.expired(rec, time.Now()) {
+					st.stale = append(st.stale, c.Value)
+				} else {
+					st.id = c.Value
+					st.created = rec.Created
+					if rec.Values != nil {
+						st.values = rec.Values
+					}
+				}
+			} else if err != ErrNotFound && err != ErrInvalidID {
+				// Unknown or malformed IDs come from the client, and only
+				// cause the cookie to be dropped
+				s.o.logError("session handler", err)
+			}
+		}
+
+		serve(h, w, r, s.key, st, s.commit, s.finish)
+	})
+}
+
+func (s *ServerSession) commit(w http.ResponseWriter, st *state) {
+	if st.id == "" && len(st.values) > 0 {
+		id, err := newID()
+		if err != nil {
+			s.o.logError("session handler", err)
+			return
+		}
+
+		st.id = id
+		st.created = time.Now()
+		st.regenerated = true
+	}
+
+	if st.id != "" {
+		if st.regenerated || !st.cookie {
+			http.SetCookie(w, s.o.cookie(st.id))
+		}
+	} else if st.cookie {
+		// Remove the cookie of an expired or destroyed session
+		http.SetCookie(w, s.o.cookie(""))
+	}
+
+	// With the session ID established, any further changes only affect the
+	// store.
+	st.late = st.id != ""
+}
+
+func (s *ServerSession) finish(st *state) {
+	for _, id := range st.stale {
+		if err := s.store.Delete(id); err != nil {
+			s.o.logError("session handler", err)
+		}
+	}
+
+	if st.id == "" {
+		return
+	}
+
+	now := time.Now()
+	rec := Record{Values: st.values, Created: st.created, Accessed: now}
+
+	if s.o.idleTimeout > 0 {
+		rec.Expires = now.Add(s.o.idleTimeout)
+	}
+
+	if s.o.absoluteTimeout > 0 {
+		if abs := st.created.Add(s.o.absoluteTimeout); rec.Expires.IsZero() || abs.Before(rec.Expires) {
+			rec.Expires = abs
+		}
+	}
+
+	if err := s.store.Save(st.id, rec); err != nil {
+		s.o.logError("session handler", err)
+	}
+}
+
+func (s *ServerSession) expired(rec Record, now time.Time) bool {
+	if rec.Expired(now) {
+		return true
+	}
+
+	if s.o.idleTimeout > 0 && now.Sub(rec.Accessed) >= s.o.idleTimeout {
+		return true
+	}
+
+	return s.o.absoluteTimeout > 0 && now.Sub(rec.Created) >= s.o.absoluteTimeout
+}
+
+func (s *ServerSession) sweep() {
+	ticker := time.NewTicker(s.o.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.store.Sweep(now); err != nil {
+				s.o.logError("session sweeper", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
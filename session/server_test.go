@@ -0,0 +1,197 @@
+package session_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/urandom/handler"
+	"github.com/urandom/handler/session"
+)
+
+func TestServerSession(t *testing.T) {
+	store := session.NewMemoryStore(0)
+	s := session.NewServerSession(store, session.SweepInterval(0))
+	defer s.Close()
+
+	var _ handler.ExtendedSession = s
+
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if err := s.Set(r, "user", "frank"); err != nil {
+				t.Fatalf("set: %s", err)
+			}
+
+			if err := s.Regenerate(r); err != nil {
+				t.Fatalf("regenerate: %s", err)
+			}
+		case "/logout":
+			if err := s.Destroy(r); err != nil {
+				t.Fatalf("destroy: %s", err)
+			}
+		}
+
+		val, err := s.Get(r, "user")
+		if err != nil {
+			t.Fatalf("get: %s", err)
+		}
+		w.Write([]byte(val))
+	}))
+
+	serve := func(path string, c *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+		rec := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "http://localhost:8080"+path, nil)
+		if c != nil {
+			r.AddCookie(c)
+		}
+		h.ServeHTTP(rec, r)
+
+		if cookies := rec.Result().Cookies(); len(cookies) > 0 {
+			return rec, cookies[0]
+		}
+
+		return rec, nil
+	}
+
+	if _, c := serve("/", nil); c != nil {
+		t.Fatalf("empty session created a cookie")
+	}
+
+	_, first := serve("/login", nil)
+	if first == nil || first.Value == "" {
+		t.Fatalf("expected a session cookie")
+	}
+
+	_, second := serve("/login", first)
+	if second == nil || second.Value == first.Value {
+		t.Fatalf("expected a regenerated session id")
+	}
+
+	if _, err := store.Load(first.Value); err != session.ErrNotFound {
+		t.Fatalf("expected the old session to be removed, got %v", err)
+	}
+
+	rec, c := serve("/", second)
+	if rec.Body.String() != "frank" {
+		t.Fatalf("expected session value %s, got %s", "frank", rec.Body.String())
+	}
+
+	if c != nil {
+		t.Fatalf("unexpected cookie for an existing session")
+	}
+
+	rec, c = serve("/logout", second)
+	if rec.Body.String() != "" {
+		t.Fatalf("expected an empty session after destroy, got %s", rec.Body.String())
+	}
+
+	if c == nil || c.MaxAge >= 0 {
+		t.Fatalf("expected the session cookie to be removed")
+	}
+
+	if store.Len() != 0 {
+		t.Fatalf("expected an empty store, got %d records", store.Len())
+	}
+}
+
+func TestServerSessionTimeouts(t *testing.T) {
+	cases := []struct {
+		name string
+		rec  session.Record
+		opts []session.Option
+	}{
+		{"idle", session.Record{Created: time.Now(), Accessed: time.Now().Add(-time.Hour)}, []session.Option{session.IdleTimeout(time.Minute)}},
+		{"absolute", session.Record{Created: time.Now().Add(-48 * time.Hour), Accessed: time.Now()}, []session.Option{session.IdleTimeout(0)}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := session.NewMemoryStore(0)
+			s := session.NewServerSession(store, append(tc.opts, session.SweepInterval(0))...)
+			defer s.Close()
+
+			tc.rec.Values = map[string]string{"user": "frank"}
+			store.Save("expired", tc.rec)
+
+			rec := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			r.AddCookie(&http.Cookie{Name: "session", Value: "expired"})
+
+			s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				val, _ := s.Get(r, "user")
+				w.Write([]byte(val))
+			})).ServeHTTP(rec, r)
+
+			if rec.Body.String() != "" {
+				t.Fatalf("expected an expired session, got value %s", rec.Body.String())
+			}
+
+			if store.Len() != 0 {
+				t.Fatalf("expected the expired session to be removed")
+			}
+		})
+	}
+}
+
+func TestServerSessionSweep(t *testing.T) {
+	store := session.NewMemoryStore(0)
+	s := session.NewServerSession(store, session.SweepInterval(10*time.Millisecond))
+	defer s.Close()
+
+	store.Save("expired", session.Record{Expires: time.Now().Add(-time.Second)})
+
+	for i := 0; i < 100 && store.Len() > 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if store.Len() != 0 {
+		t.Fatalf("expected expired records to be swept")
+	}
+}
+
+type printLogger struct {
+	messages []string
+}
+
+func (l *printLogger) Print(v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(v...))
+}
+
+func TestServerSessionInvalidID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	l := &printLogger{}
+	s := session.NewServerSession(store, session.SweepInterval(0), session.Logger(l))
+	defer s.Close()
+
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, id := range []string{"../../etc/passwd", "unknown"} {
+		rec := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: id})
+		h.ServeHTTP(rec, r)
+
+		if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+			t.Fatalf("expected the cookie with id %q to be dropped, got %v", id, cookies)
+		}
+	}
+
+	if len(l.messages) != 0 {
+		t.Fatalf("expected no errors to be logged, got %v", l.messages)
+	}
+}
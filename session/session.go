@@ -0,0 +1,315 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/urandom/handler"
+)
+
+var (
+	// ErrNoSession is returned when the request doesn't contain any session
+	// data, usually because the session handler wasn't invoked for it.
+	ErrNoSession = errors.New("session: no session in request")
+
+	// ErrCommitted is returned when a session value is modified after the
+	// response header has been written, and the change can no longer be
+	// stored in the response.
+	ErrCommitted = errors.New("session: response already committed")
+)
+
+type options struct {
+	name     string
+	path     string
+	domain   string
+	maxAge   int
+	secure   bool
+	httpOnly bool
+	sameSite http.SameSite
+	logger   handler.Logger
+	slogger  handler.StructuredLogger
+
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+	sweepInterval   time.Duration
+}
+
+// An Option is used to change the default behaviour of the sessions.
+type Option struct {
+	f func(o *options)
+}
+
+// Name sets the name of the session cookie. It defaults to 'session'.
+func Name(n string) Option {
+	return Option{func(o *options) {
+		o.name = n
+	}}
+}
+
+// Path sets the path attribute of the session cookie. It defaults to '/'.
+func Path(p string) Option {
+	return Option{func(o *options) {
+		o.path = p
+	}}
+}
+
+// Domain sets the domain attribute of the session cookie.
+func Domain(d string) Option {
+	return Option{func(o *options) {
+		o.domain = d
+	}}
+}
+
+// MaxAge sets the max age of the session cookie, in seconds. A value of 0
+// produces a cookie that expires when the browser is closed. It defaults to
+// 30 days.
+func MaxAge(age int) Option {
+	return Option{func(o *options) {
+		o.maxAge = age
+	}}
+}
+
+// Secure controls whether the session cookie will only be sent over HTTPS.
+func Secure(s bool) Option {
+	return Option{func(o *options) {
+		o.secure = s
+	}}
+}
+
+// HTTPOnly controls whether the session cookie will be hidden from client
+// scripts. It defaults to true.
+func HTTPOnly(h bool) Option {
+	return Option{func(o *options) {
+		o.httpOnly = h
+	}}
+}
+
+// SameSite sets the SameSite attribute of the session cookie. It defaults to
+// http.SameSiteLaxMode.
+func SameSite(s http.SameSite) Option {
+	return Option{func(o *options) {
+		o.sameSite = s
+	}}
+}
+
+// Logger is used to print out any error messages that occur while loading
+// or saving a session. If none is provided, no error message will be printed.
+func Logger(l handler.Logger) Option {
+	return Option{func(o *options) {
+		o.logger = l
+	}}
+}
+
+// StructuredLogger defines a logger that will receive errors from loading,
+// saving or sweeping sessions, along with the failing component. If set, it
+// takes precedence over the one provided by the Logger option.
+func StructuredLogger(l handler.StructuredLogger) Option {
+	return Option{func(o *options) {
+		o.slogger = l
+	}}
+}
+
+// IdleTimeout sets the duration of inactivity after which a server-side
+// session expires. A value of 0 disables it. It defaults to 30 minutes.
+func IdleTimeout(d time.Duration) Option {
+	return Option{func(o *options) {
+		o.idleTimeout = d
+	}}
+}
+
+// AbsoluteTimeout sets the duration after the creation of a server-side
+// session when it expires, regardless of any activity. A value of 0 disables
+// it. It defaults to 24 hours.
+func AbsoluteTimeout(d time.Duration) Option {
+	return Option{func(o *options) {
+		o.absoluteTimeout = d
+	}}
+}
+
+// SweepInterval sets how often expired server-side sessions are removed from
+// the store in the background. A value of 0 disables sweeping. It defaults to
+// 5 minutes.
+func SweepInterval(d time.Duration) Option {
+	return Option{func(o *options) {
+		o.sweepInterval = d
+	}}
+}
+
+type contextKey struct {
+	owner interface{}
+}
+
+// state holds the session data of a single request.
+type state struct {
+	mu        sync.Mutex
+	values    map[string]string
+	dirty     bool
+	committed bool
+
+	// late allows modifications after the response has been committed,
+	// when they don't need to be reflected in the response.
+	late bool
+
+	// The following are only used by server-side sessions.
+	id          string
+	created     time.Time
+	cookie      bool
+	regenerated bool
+	stale       []string
+}
+
+func defaultOptions() options {
+	return options{
+		name:     "session",
+		path:     "/",
+		maxAge:   30 * 24 * 60 * 60,
+		httpOnly: true,
+		sameSite: http.SameSiteLaxMode,
+		logger:   handler.NopLogger(),
+
+		idleTimeout:     30 * time.Minute,
+		absoluteTimeout: 24 * time.Hour,
+		sweepInterval:   5 * time.Minute,
+	}
+}
+
+func (o *options) apply(opts []Option) {
+	for _, op := range opts {
+		op.f(o)
+	}
+}
+
+// logError prints out an error that occurred in the given component.
+func (o options) logError(component string, err error) {
+	handler.Structured(o.slogger, o.logger).Log(handler.LevelError, component, handler.Field{Key: "error", Value: err})
+}
+
+// cookie creates a session cookie with the configured attributes.
+func (o options) cookie(value string) *http.Cookie {
+	c := &http.Cookie{
+		Name:     o.name,
+		Value:    value,
+		Path:     o.path,
+		Domain:   o.domain,
+		MaxAge:   o.maxAge,
+		Secure:   o.secure,
+		HttpOnly: o.httpOnly,
+		SameSite: o.sameSite,
+	}
+
+	if value == "" {
+		c.MaxAge = -1
+	}
+
+	return c
+}
+
+func stateFromRequest(key contextKey, r *http.Request) (*state, error) {
+	if st, ok := r.Context().Value(key).(*state); ok {
+		return st, nil
+	}
+
+	return nil, ErrNoSession
+}
+
+func (st *state) get(key string) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.values[key]
+}
+
+func (st *state) set(key, value string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.committed && !st.late {
+		return ErrCommitted
+	}
+
+	if st.values[key] != value {
+		st.values[key] = value
+		st.dirty = true
+	}
+
+	return nil
+}
+
+func (st *state) delete(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.committed && !st.late {
+		return ErrCommitted
+	}
+
+	if _, ok := st.values[key]; ok {
+		delete(st.values, key)
+		st.dirty = true
+	}
+
+	return nil
+}
+
+func (st *state) keys() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	keys := make([]string, 0, len(st.values))
+	for k := range st.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (st *state) clear() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.committed && !st.late {
+		return ErrCommitted
+	}
+
+	if len(st.values) > 0 {
+		st.values = map[string]string{}
+		st.dirty = true
+	}
+
+	return nil
+}
+
+// serve invokes handler h with the session state stored in the request
+// context. The commit function is called once, with the state locked, right
+// before the response header is written, or after h returns if it wrote
+// nothing. The optional finish function is called after h returns.
+func serve(h http.Handler, w http.ResponseWriter, r *http.Request, key contextKey, st *state, commit func(w http.ResponseWriter, st *state), finish func(st *state)) {
+	wrapper := handler.NewStreamWrapper(w)
+	wrapper.BeforeWriteHeader = func(int) {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+
+		if !st.committed {
+			commit(w, st)
+			st.committed = true
+		}
+	}
+
+	h.ServeHTTP(wrapper, r.WithContext(context.WithValue(r.Context(), key, st)))
+
+	if !wrapper.Committed() {
+		wrapper.BeforeWriteHeader(http.StatusOK)
+	}
+
+	if finish != nil {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+
+		finish(st)
+	}
+}
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when no record exists for a given
+// session ID, or it has already expired.
+var ErrNotFound = errors.New("session: not found")
+
+// Record is a server-side session, as kept by a Store.
+type Record struct {
+	// Values contains the session data.
+	Values map[string]string
+
+	// Created is the time when the session was first stored.
+	Created time.Time
+
+	// Accessed is the time of the last request that used the session.
+	Accessed time.Time
+
+	// Expires is the time after which the session is no longer valid.
+	Expires time.Time
+}
+
+// Store keeps server-side session records, keyed by their session ID.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the record stored under id. If no such record exists,
+	// or it has expired, ErrNotFound is returned.
+	Load(id string) (Record, error)
+
+	// Save stores the record under id, replacing any previous one.
+	Save(id string, rec Record) error
+
+	// Delete removes the record stored under id, if any.
+	Delete(id string) error
+
+	// Sweep removes all records that have expired before now.
+	Sweep(now time.Time) error
+}
+
+// Expired returns true if the record is no longer valid at the given time.
+func (rec Record) Expired(now time.Time) bool {
+	return !rec.Expires.IsZero() && !now.Before(rec.Expires)
+}
+
+func copyValues(values map[string]string) map[string]string {
+	c := make(map[string]string, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,109 @@
+package handler_test
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+type basicSession map[string]string
+
+func (s basicSession) Get(r *http.Request, key string) (string, error) {
+	return s[key], nil
+}
+
+func (s basicSession) Set(r *http.Request, key string, value string) error {
+	s[key] = value
+	return nil
+}
+
+type extendedSession struct {
+	basicSession
+}
+
+func (s extendedSession) Delete(r *http.Request, key string) error {
+	delete(s.basicSession, key)
+	return nil
+}
+
+func (s extendedSession) Keys(r *http.Request) ([]string, error) {
+	keys := []string{}
+	for k := range s.basicSession {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s extendedSession) Clear(r *http.Request) error {
+	for k := range s.basicSession {
+		delete(s.basicSession, k)
+	}
+	return nil
+}
+
+func TestSessionHelpers(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+
+	basic := basicSession{"a": "1"}
+
+	if err := handler.DeleteSessionValue(basic, r, "a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if v, ok := basic["a"]; !ok || v != "" {
+		t.Fatalf("expected the value to be emptied, got %q", v)
+	}
+
+	if _, err := handler.SessionKeys(basic, r); err != handler.ErrSessionUnsupported {
+		t.Fatalf("expected %v, got %v", handler.ErrSessionUnsupported, err)
+	}
+
+	if err := handler.ClearSession(basic, r); err != handler.ErrSessionUnsupported {
+		t.Fatalf("expected %v, got %v", handler.ErrSessionUnsupported, err)
+	}
+
+	ext := extendedSession{basicSession{"a": "1", "b": "2"}}
+
+	if err := handler.DeleteSessionValue(ext, r, "a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if keys, err := handler.SessionKeys(ext, r); err != nil || len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("unexpected keys %v, error %v", keys, err)
+	}
+
+	if err := handler.ClearSession(ext, r); err != nil || len(ext.basicSession) != 0 {
+		t.Fatalf("expected an empty session, got %v, error %v", ext.basicSession, err)
+	}
+}
+
+func TestSessionValue(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	s := basicSession{}
+
+	var u user
+	if err := handler.GetSessionValue(s, r, "user", &u); err != handler.ErrSessionValueNotFound {
+		t.Fatalf("expected %v, got %v", handler.ErrSessionValueNotFound, err)
+	}
+
+	if err := handler.SetSessionValue(s, r, "user", user{42, "frank"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := handler.GetSessionValue(s, r, "user", &u); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if u.ID != 42 || u.Name != "frank" {
+		t.Fatalf("unexpected value %v", u)
+	}
+}
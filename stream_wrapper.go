@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// StreamWrapper is a pass-through http.ResponseWriter. Unlike ResponseWrapper,
+// it doesn't buffer the response body, but writes it directly to the wrapped
+// writer, while recording the status code and the number of bytes written.
+//
+// The http.Flusher, http.Hijacker, io.ReaderFrom and http.Pusher interfaces
+// are forwarded to the wrapped writer.
+type StreamWrapper struct {
+	// Code is the status code sent to the client. It is zero until the
+	// header has been written.
+	Code int
+
+	// Written is the number of body bytes written to the client.
+	Written int64
+
+	// Hijacked will be set to true if the original http.ResponseWriter was
+	// hijacked successfully.
+	Hijacked bool
+
+	// BeforeWriteHeader, if set, is invoked right before the status code is
+	// sent to the wrapped writer, and may still modify the header map.
+	BeforeWriteHeader func(code int)
+
+	writer http.ResponseWriter
+}
+
+// NewStreamWrapper creates a new pass-through wrapper around w.
+func NewStreamWrapper(w http.ResponseWriter) *StreamWrapper {
+	return &StreamWrapper{writer: w}
+}
+
+// Header returns the header map of the wrapped writer.
+func (w *StreamWrapper) Header() http.Header {
+	return w.writer.Header()
+}
+
+// WriteHeader sends the status code to the wrapped writer. Informational
+// (1xx) codes are passed through without committing the response. Once the
+// response has been committed, subsequent calls are ignored.
+func (w *StreamWrapper) WriteHeader(code int) {
+	if w.Code != 0 {
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.writer.WriteHeader(code)
+		return
+	}
+
+	w.Code = code
+	if w.BeforeWriteHeader != nil {
+		w.BeforeWriteHeader(code)
+	}
+
+	w.writer.WriteHeader(code)
+}
+
+// Write writes b to the wrapped writer, sending a 200 OK status first if no
+// header has been written yet.
+func (w *StreamWrapper) Write(b []byte) (int, error) {
+	if w.Code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.writer.Write(b)
+	w.Written += int64(n)
+
+	return n, err
+}
+
+// ReadFrom copies the contents of r to the wrapped writer, using its
+// io.ReaderFrom implementation if available.
+func (w *StreamWrapper) ReadFrom(r io.Reader) (int64, error) {
+	if w.Code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+	if rf, ok := w.writer.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.writer, r)
+	}
+	w.Written += n
+
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the wrapped writer is an
+// http.Flusher.
+func (w *StreamWrapper) Flush() {
+	if flusher, ok := w.writer.(http.Flusher); ok {
+		if w.Code == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Hijack tries to use the original http.ResponseWriter for hijacking. If the
+// original writer doesn't implement http.Hijacker, it returns an error.
+func (w *StreamWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.writer.(http.Hijacker); ok {
+		c, rw, err := hijacker.Hijack()
+
+		if err == nil {
+			w.Hijacked = true
+		}
+
+		return c, rw, err
+	}
+
+	return nil, nil, errors.New("Wrapped ResponseWriter is not a Hijacker")
+}
+
+// Push initiates an HTTP/2 server push, if the wrapped writer is an
+// http.Pusher. Otherwise, http.ErrNotSupported is returned.
+func (w *StreamWrapper) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.writer.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// CloseNotify tries to use the original http.ResponseWriter for close
+// notification. If the original writer doesn't implement http.CloseNotifier,
+// it returns a channel that will never close.
+func (w *StreamWrapper) CloseNotify() <-chan bool {
+	if notifier, ok := w.writer.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+
+	return make(chan bool)
+}
+
+// Committed returns true if the response header has already been sent to the
+// client, or the connection has been hijacked.
+func (w *StreamWrapper) Committed() bool {
+	return w.Code != 0 || w.Hijacked
+}
+
+// Status returns the status code of the response. If the handler didn't
+// write a header, 200 OK is assumed, as that is what the server will send.
+func (w *StreamWrapper) Status() int {
+	if w.Code == 0 {
+		return http.StatusOK
+	}
+
+	return w.Code
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use by
+// http.ResponseController.
+func (w *StreamWrapper) Unwrap() http.ResponseWriter {
+	return w.writer
+}
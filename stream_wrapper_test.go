@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler"
+)
+
+type flushWriter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (w *flushWriter) Flush() {
+	w.flushes++
+	w.ResponseRecorder.Flush()
+}
+
+type pushWriter struct {
+	*httptest.ResponseRecorder
+	target string
+}
+
+func (w *pushWriter) Push(target string, opts *http.PushOptions) error {
+	w.target = target
+	return nil
+}
+
+func TestStreamWrapper(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wrapper := handler.NewStreamWrapper(rec)
+
+	if wrapper.Committed() {
+		t.Fatalf("wrapper committed before writing")
+	}
+
+	if wrapper.Status() != http.StatusOK {
+		t.Fatalf("expected implicit status %d, got %d", http.StatusOK, wrapper.Status())
+	}
+
+	wrapper.Header().Set("X-Test", "test")
+	wrapper.WriteHeader(http.StatusAccepted)
+	wrapper.WriteHeader(http.StatusInternalServerError)
+
+	if _, err := wrapper.Write([]byte("Test 1")); err != nil {
+		t.Fatalf("unexpected write error: %s", err)
+	}
+
+	if _, err := wrapper.ReadFrom(strings.NewReader(" Test 2")); err != nil {
+		t.Fatalf("unexpected read from error: %s", err)
+	}
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected code %d, got %d", http.StatusAccepted, rec.Code)
+	}
+
+	if wrapper.Code != http.StatusAccepted {
+		t.Fatalf("expected recorded code %d, got %d", http.StatusAccepted, wrapper.Code)
+	}
+
+	if rec.Header().Get("X-Test") != "test" {
+		t.Fatalf("header wasn't passed through")
+	}
+
+	if rec.Body.String() != "Test 1 Test 2" {
+		t.Fatalf("expected body %q, got %q", "Test 1 Test 2", rec.Body.String())
+	}
+
+	if wrapper.Written != int64(rec.Body.Len()) {
+		t.Fatalf("expected %d written bytes, got %d", rec.Body.Len(), wrapper.Written)
+	}
+
+	if !wrapper.Committed() {
+		t.Fatalf("wrapper isn't marked as committed")
+	}
+}
+
+func TestStreamWrapperInterfaces(t *testing.T) {
+	fw := &flushWriter{ResponseRecorder: httptest.NewRecorder()}
+
+	var wrapper http.ResponseWriter = handler.NewStreamWrapper(fw)
+
+	wrapper.(http.Flusher).Flush()
+	if fw.flushes != 1 {
+		t.Fatalf("flush wasn't forwarded")
+	}
+
+	if wrapper.(*handler.StreamWrapper).Code != http.StatusOK {
+		t.Fatalf("flush didn't commit the response")
+	}
+
+	if _, ok := wrapper.(io.ReaderFrom); !ok {
+		t.Fatalf("wrapper isn't a reader from")
+	}
+
+	if err := wrapper.(http.Pusher).Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("expected %v, got %v", http.ErrNotSupported, err)
+	}
+
+	pw := &pushWriter{ResponseRecorder: httptest.NewRecorder()}
+	wrapper = handler.NewStreamWrapper(pw)
+
+	if err := wrapper.(http.Pusher).Push("/style.css", nil); err != nil {
+		t.Fatalf("unexpected push error: %s", err)
+	}
+
+	if pw.target != "/style.css" {
+		t.Fatalf("push wasn't forwarded")
+	}
+
+	hw := &hijackWriter{httptest.NewRecorder(), false}
+	wrapper = handler.NewStreamWrapper(hw)
+
+	if _, _, err := wrapper.(http.Hijacker).Hijack(); err != nil {
+		t.Fatalf("error wasn't expected: %s", err)
+	}
+
+	if !hw.hijacked || !wrapper.(*handler.StreamWrapper).Hijacked {
+		t.Fatalf("writer wasn't hijacked")
+	}
+}
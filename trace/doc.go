@@ -0,0 +1,5 @@
+/*
+Package trace provides handlers for correlating the requests of a service
+with its logs and with the requests made to other services.
+*/
+package trace
@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// An Exporter receives the finished spans of the Trace handler. It is called
+// synchronously, and has to be safe for concurrent use.
+type Exporter interface {
+	Export(span Span)
+}
+
+// The ExporterFunc type is an adapter to allow using ordinary functions as
+// exporters.
+type ExporterFunc func(span Span)
+
+func (f ExporterFunc) Export(span Span) {
+	f(span)
+}
+
+// MemoryExporter keeps all exported spans in memory. It is mostly useful
+// for tests.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewMemoryExporter creates a new, empty, memory exporter.
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+// Export stores the span.
+func (e *MemoryExporter) Export(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns a copy of the stored spans, in the order they were
+// exported.
+func (e *MemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	spans := make([]Span, len(e.spans))
+	copy(spans, e.spans)
+
+	return spans
+}
+
+// Reset removes all stored spans.
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = nil
+}
+
+// JSONExporter writes each span as a single line JSON object, with the
+// following keys:
+//
+//	name, trace_id, span_id, parent_id, start, end, duration_us, attributes
+//
+// The parent ID is omitted for root spans. Use os.Stdout as the writer to
+// print the spans to the standard output.
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type jsonSpan struct {
+	Name       string                 `json:"name"`
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Start      string                 `json:"start"`
+	End        string                 `json:"end"`
+	Duration   int64                  `json:"duration_us"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// NewJSONExporter creates a new exporter that writes to w.
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// Export writes the span. Any errors are ignored.
+func (e *JSONExporter) Export(span Span) {
+	js := jsonSpan{
+		Name:       span.Name,
+		TraceID:    span.Context.TraceID.String(),
+		SpanID:     span.Context.SpanID.String(),
+		Start:      span.Start.Format(time.RFC3339Nano),
+		End:        span.End.Format(time.RFC3339Nano),
+		Duration:   int64(span.Duration() / time.Microsecond),
+		Attributes: map[string]interface{}{},
+	}
+
+	if span.ParentID.IsValid() {
+		js.ParentID = span.ParentID.String()
+	}
+
+	for _, a := range span.Attributes {
+		if d, ok := a.Value.(time.Duration); ok {
+			js.Attributes[a.Key] = d.String()
+		} else {
+			js.Attributes[a.Key] = a.Value
+		}
+	}
+
+	b, err := json.Marshal(js)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fmt.Fprintf(e.w, "%s\n", b)
+}
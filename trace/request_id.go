@@ -0,0 +1,171 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the default header used to receive and send request
+// IDs.
+const RequestIDHeader = "X-Request-Id"
+
+type contextKey string
+
+// RequestIDKey is the request context key that points to the request ID.
+const RequestIDKey contextKey = "request-id"
+
+type options struct {
+	header         string
+	generator      func() string
+	maxLength      int
+	ignoreIncoming bool
+	route          func(r *http.Request) string
+	exporter       Exporter
+}
+
+// An Option is used to change the default behaviour of the handlers.
+type Option struct {
+	f func(o *options)
+}
+
+// IgnoreIncoming causes the RequestID handler to always generate a new ID,
+// ignoring any provided by the client.
+var IgnoreIncoming = Option{func(o *options) {
+	o.ignoreIncoming = true
+}}
+
+// Header sets the header used to receive and send request IDs. The default
+// is RequestIDHeader.
+func Header(name string) Option {
+	return Option{func(o *options) {
+		o.header = name
+	}}
+}
+
+// Generator sets the function used to generate new request IDs. The default
+// is UUIDv7.
+func Generator(g func() string) Option {
+	return Option{func(o *options) {
+		o.generator = g
+	}}
+}
+
+// MaxLength sets the maximum length of an incoming request ID. Longer ones
+// are replaced by a generated ID. The default is 128.
+func MaxLength(n int) Option {
+	return Option{func(o *options) {
+		o.maxLength = n
+	}}
+}
+
+// RequestID returns a handler that assigns an ID to each request, before
+// invoking the handler h. The ID provided by the client in the request
+// header is used if it is valid: no longer than the maximum length, and only
+// consisting of letters, digits and the '-', '_', '.', ':', '+', '/', '=' and
+// '@' characters. Otherwise, a new one is generated.
+//
+// The ID is stored in the request context under RequestIDKey, and is echoed
+// in the response header. The log handlers automatically include it in their
+// output, as long as they are wrapped by this handler.
+func RequestID(h http.Handler, opts ...Option) http.Handler {
+	o := options{header: RequestIDHeader, generator: UUIDv7, maxLength: 128}
+	o.apply(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(o.header)
+		if o.ignoreIncoming || !validRequestID(id, o.maxLength) {
+			id = o.generator()
+		}
+
+		w.Header().Set(o.header, id)
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), RequestIDKey, id)))
+	})
+}
+
+// RequestIDFrom returns the request ID stored in the context, or an empty
+// string if there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// UUIDv7 returns a new, time-ordered, RFC 9562 version 7 UUID.
+func UUIDv7() string {
+	var u [16]byte
+	rand.Read(u[6:])
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(u[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+
+	return string(buf)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a new, time-ordered, Universally Unique Lexicographically
+// Sortable Identifier.
+func ULID() string {
+	var u [16]byte
+	rand.Read(u[6:])
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(u[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+
+	// 26 characters of 5 bits each, encoding the 128 bit value with two
+	// leading zero bits
+	buf := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(buf)
+}
+
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=', c == '@':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o *options) apply(opts []Option) {
+	for _, op := range opts {
+		op.f(o)
+	}
+}
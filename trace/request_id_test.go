@@ -0,0 +1,118 @@
+package trace_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/urandom/handler/trace"
+)
+
+var (
+	uuidv7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulid   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		incoming string
+		opts     []trace.Option
+		header   string
+		expected string
+	}{
+		{"", nil, trace.RequestIDHeader, uuidv7.String()},
+		{"abc-123", nil, trace.RequestIDHeader, "^abc-123$"},
+		{"abc 123", nil, trace.RequestIDHeader, uuidv7.String()},
+		{"<script>", nil, trace.RequestIDHeader, uuidv7.String()},
+		{strings.Repeat("a", 129), nil, trace.RequestIDHeader, uuidv7.String()},
+		{strings.Repeat("a", 11), []trace.Option{trace.MaxLength(10)}, trace.RequestIDHeader, uuidv7.String()},
+		{"abc-123", []trace.Option{trace.IgnoreIncoming, trace.Generator(trace.ULID)}, trace.RequestIDHeader, ulid.String()},
+		{"abc-123", []trace.Option{trace.Header("X-Correlation-Id")}, "X-Correlation-Id", "^abc-123$"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var id string
+			h := trace.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id = trace.RequestIDFrom(r.Context())
+			}), tc.opts...)
+
+			r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+			if tc.incoming != "" {
+				r.Header.Set(tc.header, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, r)
+
+			if !regexp.MustCompile(tc.expected).MatchString(id) {
+				t.Fatalf("expected id matching %s, got %s", tc.expected, id)
+			}
+
+			if rec.Header().Get(tc.header) != id {
+				t.Fatalf("expected the id to be echoed, got %s", rec.Header().Get(tc.header))
+			}
+		})
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	seen := map[string]bool{}
+
+	prev := ""
+	for i := 0; i < 100; i++ {
+		u := trace.UUIDv7()
+		if !uuidv7.MatchString(u) {
+			t.Fatalf("invalid UUIDv7 %s", u)
+		}
+
+		l := trace.ULID()
+		if !ulid.MatchString(l) {
+			t.Fatalf("invalid ULID %s", l)
+		}
+
+		// The timestamp prefix sorts chronologically
+		if prev != "" && l[:10] < prev[:10] {
+			t.Fatalf("expected ULID %s to sort after %s", l, prev)
+		}
+		prev = l
+
+		if seen[u] || seen[l] {
+			t.Fatalf("duplicate id generated")
+		}
+		seen[u], seen[l] = true, true
+	}
+}
+
+type roundTripper struct {
+	header http.Header
+}
+
+func (rt *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.header = r.Header
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+}
+
+func TestTransport(t *testing.T) {
+	rt := &roundTripper{}
+	client := &http.Client{Transport: trace.Transport(rt)}
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	r = r.WithContext(context.WithValue(r.Context(), trace.RequestIDKey, "abc-123"))
+
+	if _, err := client.Do(r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if rt.header.Get(trace.RequestIDHeader) != "abc-123" {
+		t.Fatalf("expected the request id to be propagated, got %v", rt.header)
+	}
+
+	if r.Header.Get(trace.RequestIDHeader) != "" {
+		t.Fatalf("expected the original request to be unchanged")
+	}
+}
@@ -0,0 +1,239 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urandom/handler"
+)
+
+const (
+	// TraceparentHeader is the W3C Trace Context header carrying the trace
+	// and parent span IDs.
+	TraceparentHeader = "Traceparent"
+	// TracestateHeader is the W3C Trace Context header carrying
+	// vendor-specific trace data.
+	TracestateHeader = "Tracestate"
+)
+
+// SpanContextKey is the request context key that points to the
+// SpanContext of the current server span.
+const SpanContextKey contextKey = "span-context"
+
+// FlagSampled is the trace flag signaling that the caller may have recorded
+// the trace.
+const FlagSampled byte = 0x01
+
+// ErrInvalidTraceparent is returned when a traceparent header value can't be
+// parsed.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the part of a span that is propagated between services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	// State is the tracestate header value, passed on unchanged.
+	State string
+}
+
+// Span records the handling of a single request.
+type Span struct {
+	Name     string
+	Context  SpanContext
+	ParentID SpanID
+	Start    time.Time
+	End      time.Time
+	// Attributes include the request method, route, response status and
+	// the duration of the request.
+	Attributes []handler.Field
+}
+
+// Duration returns the time taken by the span.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// RouteFunc sets the function that provides the route of a request, used
+// for naming spans. By default, the request path is used. Callers should
+// provide one returning a route pattern, such as '/users/:id', to avoid high
+// cardinality names.
+func RouteFunc(f func(r *http.Request) string) Option {
+	return Option{func(o *options) {
+		o.route = f
+	}}
+}
+
+// SpanExporter sets the exporter that receives the finished spans of the
+// Trace handler.
+func SpanExporter(e Exporter) Option {
+	return Option{func(o *options) {
+		o.exporter = e
+	}}
+}
+
+// Trace returns a handler that creates a server span for each request. If
+// the request contains a valid traceparent header, the span joins that
+// trace, and the tracestate header is preserved. Otherwise, a new, sampled,
+// trace is started.
+//
+// The SpanContext of the span is stored in the request context under
+// SpanContextKey, so that it can be propagated to other services using
+// Transport, and included in the output of the log handlers, as long as they
+// are wrapped by this handler. Once the request is handled, sampled spans are
+// passed to the exporter, if one is provided.
+func Trace(h http.Handler, opts ...Option) http.Handler {
+	o := options{route: func(r *http.Request) string { return r.URL.Path }}
+	o.apply(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := Span{Start: time.Now()}
+
+		if parent, err := ParseTraceparent(r.Header.Get(TraceparentHeader)); err == nil {
+			span.Context = parent
+			span.ParentID = parent.SpanID
+			span.Context.State = tracestate(r.Header[TracestateHeader])
+		} else {
+			rand.Read(span.Context.TraceID[:])
+			span.Context.Flags = FlagSampled
+		}
+		rand.Read(span.Context.SpanID[:])
+
+		route := o.route(r)
+		span.Name = r.Method + " " + route
+
+		wrapper := handler.NewStreamWrapper(w)
+
+		h.ServeHTTP(wrapper, r.WithContext(context.WithValue(r.Context(), SpanContextKey, span.Context)))
+
+		span.End = time.Now()
+		span.Attributes = []handler.Field{
+			{Key: "http.request.method", Value: r.Method},
+			{Key: "http.route", Value: route},
+			{Key: "http.response.status_code", Value: wrapper.Status()},
+			{Key: "duration", Value: span.Duration()},
+		}
+
+		if o.exporter != nil && span.Context.Sampled() {
+			o.exporter.Export(span)
+		}
+	})
+}
+
+// SpanContextFrom returns the SpanContext stored in the context, and whether
+// there was one.
+func SpanContextFrom(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(SpanContextKey).(SpanContext)
+	return sc, ok
+}
+
+// ParseTraceparent parses a traceparent header value, as defined by the W3C
+// Trace Context specification.
+func ParseTraceparent(v string) (SpanContext, error) {
+	var sc SpanContext
+
+	// version-traceid-spanid-flags, with future versions possibly appending
+	// more fields
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	version, ok := decodeHex(v[:2])
+	if !ok || version[0] == 0xff || version[0] == 0 && len(v) != 55 || len(v) > 55 && v[55] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	traceID, ok := decodeHex(v[3:35])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	spanID, ok := decodeHex(v[36:52])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	flags, ok := decodeHex(v[53:55])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+
+	return sc, nil
+}
+
+// Traceparent returns the traceparent header value of the span context.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// Sampled returns whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns whether the ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns whether the ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// decodeHex decodes lowercase hex strings only, as required by the
+// specification.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// tracestate combines the header values, discarding them if there are more
+// list members than allowed by the specification.
+func tracestate(values []string) string {
+	state := strings.Join(values, ",")
+
+	members := 0
+	for _, m := range strings.Split(state, ",") {
+		if strings.TrimSpace(m) != "" {
+			members++
+		}
+	}
+
+	if members > 32 {
+		return ""
+	}
+
+	return state
+}
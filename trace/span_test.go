@@ -0,0 +1,189 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urandom/handler/trace"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			sc, err := trace.ParseTraceparent(tc.value)
+			if tc.valid != (err == nil) {
+				t.Fatalf("expected valid to be %v, got error %v", tc.valid, err)
+			}
+
+			if !tc.valid {
+				return
+			}
+
+			if sc.Sampled() != tc.sampled {
+				t.Fatalf("expected sampled to be %v", tc.sampled)
+			}
+
+			if tc.value[:2] == "00" && sc.Traceparent() != tc.value {
+				t.Fatalf("expected %s, got %s", tc.value, sc.Traceparent())
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	cases := []struct {
+		traceparent string
+		tracestate  string
+		exported    bool
+		joined      bool
+	}{
+		{"", "", true, false},
+		{"garbage", "vendor=1", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=1,other=2", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "", false, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			exporter := trace.NewMemoryExporter()
+
+			var sc trace.SpanContext
+			h := trace.Trace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sc, _ = trace.SpanContextFrom(r.Context())
+				w.WriteHeader(http.StatusCreated)
+			}), trace.SpanExporter(exporter), trace.RouteFunc(func(r *http.Request) string {
+				return "/users/:id"
+			}))
+
+			r, _ := http.NewRequest("POST", "http://localhost:8080/users/1", nil)
+			if tc.traceparent != "" {
+				r.Header.Set("Traceparent", tc.traceparent)
+			}
+			if tc.tracestate != "" {
+				r.Header.Set("Tracestate", tc.tracestate)
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+				t.Fatalf("expected a valid span context, got %v", sc)
+			}
+
+			if tc.joined {
+				parent, _ := trace.ParseTraceparent(tc.traceparent)
+				if sc.TraceID != parent.TraceID || sc.SpanID == parent.SpanID {
+					t.Fatalf("expected to join trace %s with a new span, got %s", parent.Traceparent(), sc.Traceparent())
+				}
+
+				if sc.State != tc.tracestate {
+					t.Fatalf("expected tracestate %s, got %s", tc.tracestate, sc.State)
+				}
+			} else if sc.State != "" {
+				t.Fatalf("expected no tracestate for a new trace, got %s", sc.State)
+			}
+
+			spans := exporter.Spans()
+			if !tc.exported {
+				if len(spans) != 0 {
+					t.Fatalf("expected unsampled spans not to be exported")
+				}
+				return
+			}
+
+			if len(spans) != 1 {
+				t.Fatalf("expected a single span, got %d", len(spans))
+			}
+
+			span := spans[0]
+			if span.Name != "POST /users/:id" || span.Context.SpanID != sc.SpanID {
+				t.Fatalf("unexpected span %v", span)
+			}
+
+			if tc.joined && span.ParentID.String() != "00f067aa0ba902b7" {
+				t.Fatalf("expected parent id 00f067aa0ba902b7, got %s", span.ParentID)
+			}
+
+			attrs := map[string]interface{}{}
+			for _, a := range span.Attributes {
+				attrs[a.Key] = a.Value
+			}
+
+			if attrs["http.request.method"] != "POST" || attrs["http.route"] != "/users/:id" || attrs["http.response.status_code"] != http.StatusCreated {
+				t.Fatalf("unexpected attributes %v", attrs)
+			}
+
+			if _, ok := attrs["duration"].(time.Duration); !ok {
+				t.Fatalf("expected a duration attribute, got %v", attrs["duration"])
+			}
+		})
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	h := trace.Trace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), trace.SpanExporter(trace.NewJSONExporter(&buf)))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/foo", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var span map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &span); err != nil {
+		t.Fatalf("invalid json %s: %s", buf.String(), err)
+	}
+
+	if span["name"] != "GET /foo" || len(span["trace_id"].(string)) != 32 || len(span["span_id"].(string)) != 16 {
+		t.Fatalf("unexpected span %s", buf.String())
+	}
+
+	if _, ok := span["parent_id"]; ok {
+		t.Fatalf("expected no parent id for a root span")
+	}
+
+	attrs := span["attributes"].(map[string]interface{})
+	if attrs["http.response.status_code"] != float64(http.StatusOK) {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestTransportTraceContext(t *testing.T) {
+	rt := &roundTripper{}
+	client := &http.Client{Transport: trace.Transport(rt)}
+
+	sc, _ := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	sc.State = "vendor=1"
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080", nil)
+	r = r.WithContext(context.WithValue(r.Context(), trace.SpanContextKey, sc))
+
+	if _, err := client.Do(r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if rt.header.Get("Traceparent") != sc.Traceparent() || rt.header.Get("Tracestate") != "vendor=1" {
+		t.Fatalf("expected the trace context to be propagated, got %v", rt.header)
+	}
+}
@@ -0,0 +1,51 @@
+package trace
+
+import "net/http"
+
+type transport struct {
+	base http.RoundTripper
+	o    options
+}
+
+// Transport returns an http.RoundTripper that propagates the request ID and
+// the trace context, stored in the context of outgoing requests, to other
+// services, before passing the requests to base. Headers already present in
+// a request are left alone. If base is nil, http.DefaultTransport is
+// used.
+func Transport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	o := options{header: RequestIDHeader}
+	o.apply(opts)
+
+	return transport{base: base, o: o}
+}
+
+func (t transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	header := http.Header{}
+
+	if id := RequestIDFrom(r.Context()); id != "" && r.Header.Get(t.o.header) == "" {
+		header.Set(t.o.header, id)
+	}
+
+	if sc, ok := SpanContextFrom(r.Context()); ok && r.Header.Get(TraceparentHeader) == "" {
+		header.Set(TraceparentHeader, sc.Traceparent())
+		if sc.State != "" {
+			header.Set(TracestateHeader, sc.State)
+		}
+	}
+
+	if len(header) == 0 {
+		return t.base.RoundTrip(r)
+	}
+
+	// A RoundTripper must not modify the request
+	r = r.Clone(r.Context())
+	for k, v := range header {
+		r.Header[k] = v
+	}
+
+	return t.base.RoundTrip(r)
+}